@@ -0,0 +1,76 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+)
+
+// ContextServerList is the context-aware form of ServerList. Cancelling the
+// context should abort any in-flight requests.
+type ContextServerList interface {
+	GetServersContext(ctx context.Context) (Routes, error)
+}
+
+// ContextMcRouter is the context-aware form of McRouter. Implementations,
+// including a McRouter passed to AdaptMcRouter, must be safe for concurrent
+// use when Reconciler.ApplyConcurrency is above 1.
+type ContextMcRouter interface {
+	GetRoutesContext(ctx context.Context) (Routes, error)
+	RegisterRouteContext(ctx context.Context, route Route) error
+	DeleteRouteContext(ctx context.Context, serverAddress string) error
+}
+
+// AdaptServerList lets an existing ServerList be used where a
+// ContextServerList is required. Implementations that are already
+// context-aware are returned as is, others ignore the context.
+func AdaptServerList(sl ServerList) ContextServerList {
+	if c, ok := sl.(ContextServerList); ok {
+		return c
+	}
+	return serverListAdapter{sl}
+}
+
+// AdaptMcRouter lets an existing McRouter be used where a ContextMcRouter is
+// required. Implementations that are already context-aware are returned as
+// is, others ignore the context.
+func AdaptMcRouter(mr McRouter) ContextMcRouter {
+	if c, ok := mr.(ContextMcRouter); ok {
+		return c
+	}
+	return mcRouterAdapter{mr}
+}
+
+type serverListAdapter struct {
+	ServerList
+}
+
+func (a serverListAdapter) GetServersContext(ctx context.Context) (Routes, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.GetServers()
+}
+
+type mcRouterAdapter struct {
+	McRouter
+}
+
+func (a mcRouterAdapter) GetRoutesContext(ctx context.Context) (Routes, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return a.GetRoutes()
+}
+
+func (a mcRouterAdapter) RegisterRouteContext(ctx context.Context, route Route) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.RegisterRoute(route)
+}
+
+func (a mcRouterAdapter) DeleteRouteContext(ctx context.Context, serverAddress string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.DeleteRoute(serverAddress)
+}
@@ -0,0 +1,108 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdaptServerList(t *testing.T) {
+	sl := &mockServerList{
+		routes: Routes{{ServerAddress: "server1.example.com", Backend: "backend1:25565"}},
+	}
+	adapted := AdaptServerList(sl)
+
+	routes, err := adapted.GetServersContext(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(routes) != 1 {
+		t.Errorf("expected 1 route, got %d", len(routes))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := adapted.GetServersContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	client := NewServerListClient("http://api.example.com", &mockAuth{})
+	if AdaptServerList(client) != ContextServerList(client) {
+		t.Error("expected context-aware server list to be returned as is")
+	}
+}
+
+func TestAdaptMcRouter(t *testing.T) {
+	mr := &mockMcRouter{}
+	adapted := AdaptMcRouter(mr)
+
+	ctx := context.Background()
+	if _, err := adapted.GetRoutesContext(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := adapted.RegisterRouteContext(ctx, Route{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := adapted.DeleteRouteContext(ctx, "server1.example.com"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if mr.getRoutesCallCount != 1 || mr.registerCallCount != 1 || mr.deleteCallCount != 1 {
+		t.Error("expected every call to reach the wrapped mc router")
+	}
+
+	client := NewMcRouterClient("http://localhost:8000", McRouterClientOpts{})
+	if AdaptMcRouter(client) != ContextMcRouter(client) {
+		t.Error("expected context-aware mc router to be returned as is")
+	}
+}
+
+func TestClientsAbortOnContextCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	sl := NewServerListClientWithOpts(server.URL, &mockAuth{}, ServerListClientOpts{Retry: testRetryPolicy()})
+	mr := NewMcRouterClient(server.URL, McRouterClientOpts{Retry: testRetryPolicy()})
+
+	calls := map[string]func(ctx context.Context) error{
+		"GetServersContext": func(ctx context.Context) error {
+			_, err := sl.GetServersContext(ctx)
+			return err
+		},
+		"GetRoutesContext": func(ctx context.Context) error {
+			_, err := mr.GetRoutesContext(ctx)
+			return err
+		},
+		"RegisterRouteContext": func(ctx context.Context) error {
+			return mr.RegisterRouteContext(ctx, Route{ServerAddress: "server1.example.com", Backend: "backend1:25565"})
+		},
+		"DeleteRouteContext": func(ctx context.Context) error {
+			return mr.DeleteRouteContext(ctx, "server1.example.com")
+		},
+	}
+
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := call(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected context.DeadlineExceeded, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("expected call to abort promptly, took %s", elapsed)
+			}
+		})
+	}
+}
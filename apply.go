@@ -0,0 +1,104 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// applyPhase applies actions with up to ApplyConcurrency of them in flight,
+// storing the result of actions[i] in results[i]. Workers pick actions in
+// order, so with a single worker they are applied exactly as listed. The
+// ownership of the routes changed is recorded once the phase is done, so the
+// store is updated once per phase rather than per action.
+func (r *Reconciler) applyPhase(ctx context.Context, actions []Action, results []ActionResult, limiter *rateLimiter) {
+	workers := min(max(r.ApplyConcurrency, 1), len(actions))
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				err := limiter.wait(ctx)
+				if err != nil {
+					err = fmt.Errorf("skipped %s: %w", actions[i].ServerAddress, err)
+				} else {
+					err = r.applyAction(ctx, actions[i])
+				}
+				results[i] = ActionResult{Action: actions[i], Err: err}
+			}
+		}()
+	}
+
+	for i := range actions {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var claims, releases []string
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		switch result.Action.Type {
+		case ActionAdd, ActionUpdate:
+			claims = append(claims, result.Action.ServerAddress)
+		case ActionDelete:
+			releases = append(releases, result.Action.ServerAddress)
+		}
+	}
+	r.recordOwnership(claims, releases)
+}
+
+// rateLimiter spaces out calls to wait so that at most perSecond of them
+// return each second. A nil rateLimiter never waits.
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,258 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrentMcRouter is safe for concurrent use and records the largest
+// number of calls in flight at once.
+type concurrentMcRouter struct {
+	delay       time.Duration
+	registerErr map[string]error
+	onRegister  func(route Route)
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	calls       []string
+}
+
+func (m *concurrentMcRouter) GetRoutesContext(ctx context.Context) (Routes, error) {
+	return nil, nil
+}
+
+func (m *concurrentMcRouter) RegisterRouteContext(ctx context.Context, route Route) error {
+	m.enter("register " + route.ServerAddress)
+	defer m.exit()
+
+	if m.onRegister != nil {
+		m.onRegister(route)
+	}
+	return m.registerErr[route.ServerAddress]
+}
+
+func (m *concurrentMcRouter) DeleteRouteContext(ctx context.Context, serverAddress string) error {
+	m.enter("delete " + serverAddress)
+	defer m.exit()
+
+	return nil
+}
+
+func (m *concurrentMcRouter) enter(call string) {
+	m.mu.Lock()
+	m.inFlight++
+	m.maxInFlight = max(m.maxInFlight, m.inFlight)
+	m.calls = append(m.calls, call)
+	m.mu.Unlock()
+
+	time.Sleep(m.delay)
+}
+
+func (m *concurrentMcRouter) exit() {
+	m.mu.Lock()
+	m.inFlight--
+	m.mu.Unlock()
+}
+
+func addActions(n int) []Action {
+	actions := make([]Action, n)
+	for i := range actions {
+		actions[i] = Action{Type: ActionAdd, ServerAddress: fmt.Sprintf("server%02d.example.com", i), Backend: "backend:25565"}
+	}
+	return actions
+}
+
+func TestApplyConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		expectedMax int
+	}{
+		{name: "unset applies one by one", concurrency: 0, expectedMax: 1},
+		{name: "one by one", concurrency: 1, expectedMax: 1},
+		{name: "bounded", concurrency: 4, expectedMax: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := &concurrentMcRouter{
+				delay:       10 * time.Millisecond,
+				registerErr: map[string]error{"server05.example.com": errors.New("connection refused")},
+			}
+			reconciler := NewReconcilerContext(nil, mr, 0)
+			reconciler.ApplyConcurrency = tt.concurrency
+			reconciler.Ownership = NewMemoryOwnershipStore()
+
+			actions := addActions(12)
+			result, err := reconciler.Apply(context.Background(), actions)
+			if err == nil || !strings.Contains(err.Error(), "failed to register route server05.example.com") {
+				t.Errorf("expected the failed register to be reported, got %v", err)
+			}
+
+			if mr.maxInFlight != tt.expectedMax {
+				t.Errorf("expected at most %d actions in flight, got %d", tt.expectedMax, mr.maxInFlight)
+			}
+			if len(mr.calls) != len(actions) {
+				t.Errorf("expected %d calls, got %d", len(actions), len(mr.calls))
+			}
+
+			for i, res := range result.Results {
+				if res.Action != actions[i] {
+					t.Errorf("expected result %d to be for %s, got %s", i, actions[i].ServerAddress, res.Action.ServerAddress)
+				}
+				if (res.Err != nil) != (i == 5) {
+					t.Errorf("unexpected error for %s: %v", res.Action.ServerAddress, res.Err)
+				}
+			}
+			if len(result.Failed()) != 1 || len(result.Succeeded()) != 11 {
+				t.Errorf("expected 1 failed and 11 succeeded actions, got %d and %d", len(result.Failed()), len(result.Succeeded()))
+			}
+
+			owned, err := reconciler.Ownership.Owned()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(owned) != 11 || owned["server05.example.com"] {
+				t.Errorf("expected the 11 registered routes to be owned, got %v", owned)
+			}
+		})
+	}
+}
+
+func TestApplyPhases(t *testing.T) {
+	mr := &concurrentMcRouter{delay: 5 * time.Millisecond}
+	reconciler := NewReconcilerContext(nil, mr, 0)
+	reconciler.ApplyConcurrency = 8
+
+	actions := reconciler.orderActions(append(addActions(6),
+		Action{Type: ActionDelete, ServerAddress: "old1.example.com"},
+		Action{Type: ActionDelete, ServerAddress: "old2.example.com"},
+	))
+	if _, err := reconciler.Apply(context.Background(), actions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(mr.calls) != 8 {
+		t.Fatalf("expected 8 calls, got %v", mr.calls)
+	}
+	for _, call := range mr.calls[:6] {
+		if !strings.HasPrefix(call, "register ") {
+			t.Errorf("expected every add before the deletes, got %v", mr.calls)
+			break
+		}
+	}
+}
+
+// batchingOwnershipStore records each Update made to a MemoryOwnershipStore.
+type batchingOwnershipStore struct {
+	*MemoryOwnershipStore
+
+	updates [][2][]string
+}
+
+func (s *batchingOwnershipStore) Claim(serverAddress string) error {
+	return errors.New("expected ownership to be recorded with Update")
+}
+
+func (s *batchingOwnershipStore) Release(serverAddress string) error {
+	return errors.New("expected ownership to be recorded with Update")
+}
+
+func (s *batchingOwnershipStore) Update(claims, releases []string) error {
+	s.updates = append(s.updates, [2][]string{claims, releases})
+	return s.MemoryOwnershipStore.Update(claims, releases)
+}
+
+func TestApplyOwnershipPerPhase(t *testing.T) {
+	mr := &concurrentMcRouter{registerErr: map[string]error{"server02.example.com": errors.New("connection refused")}}
+	store := &batchingOwnershipStore{MemoryOwnershipStore: NewMemoryOwnershipStore()}
+	reconciler := NewReconcilerContext(nil, mr, 0)
+	reconciler.ApplyConcurrency = 8
+	reconciler.Ownership = store
+
+	actions := reconciler.orderActions(append(addActions(6),
+		Action{Type: ActionDelete, ServerAddress: "old1.example.com"},
+		Action{Type: ActionDelete, ServerAddress: "old2.example.com"},
+	))
+	if _, err := reconciler.Apply(context.Background(), actions); err == nil {
+		t.Fatal("expected the failed register to be reported")
+	}
+
+	if len(store.updates) != 2 {
+		t.Fatalf("expected one ownership update per phase, got %v", store.updates)
+	}
+	if claims := store.updates[0][0]; len(claims) != 5 || slices.Contains(claims, "server02.example.com") {
+		t.Errorf("expected the 5 registered routes to be claimed together, got %v", claims)
+	}
+	if releases := store.updates[1][1]; len(releases) != 2 {
+		t.Errorf("expected the 2 deleted routes to be released together, got %v", releases)
+	}
+}
+
+func TestApplyRateLimit(t *testing.T) {
+	mr := &concurrentMcRouter{}
+	reconciler := NewReconcilerContext(nil, mr, 0)
+	reconciler.ApplyConcurrency = 6
+	reconciler.ApplyRateLimit = 50
+
+	start := time.Now()
+	if _, err := reconciler.Apply(context.Background(), addActions(6)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The first action starts immediately, the other five 20ms apart.
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected 6 actions at 50 per second to take at least 100ms, took %v", elapsed)
+	}
+}
+
+func TestApplyCancel(t *testing.T) {
+	tests := []struct {
+		name      string
+		rateLimit float64
+	}{
+		{name: "unlimited"},
+		// Cancelling must not wait for the rate limiter.
+		{name: "rate limited", rateLimit: 0.1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			mr := &concurrentMcRouter{onRegister: func(Route) { cancel() }}
+			reconciler := NewReconcilerContext(nil, mr, 0)
+			reconciler.ApplyConcurrency = 1
+			reconciler.ApplyRateLimit = tt.rateLimit
+
+			start := time.Now()
+			result, err := reconciler.Apply(ctx, addActions(4))
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("expected a cancelled apply to return promptly, took %v", elapsed)
+			}
+
+			if len(mr.calls) != 1 {
+				t.Errorf("expected only the first action to be sent, got %v", mr.calls)
+			}
+			if len(result.Succeeded()) != 1 {
+				t.Errorf("expected 1 succeeded action, got %d", len(result.Succeeded()))
+			}
+			for _, res := range result.Results[1:] {
+				if res.Err == nil || !strings.HasPrefix(res.Err.Error(), "skipped ") {
+					t.Errorf("expected %s to be skipped, got %v", res.Action.ServerAddress, res.Err)
+				}
+			}
+		})
+	}
+}
@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
 	"syscall"
 
 	mcrouterdiscovery "github.com/Seedloaf/mc-router-discovery"
 	"github.com/Seedloaf/mc-router-discovery/auth"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 func main() {
@@ -18,28 +25,215 @@ func main() {
 		log.Fatalf("Invalid configuration: %s", err)
 	}
 
-	configureLogger(cfg.LogLevel)
+	var logLevel slog.LevelVar
+	logLevel.Set(cfg.LogLevel)
+	configureLogger(&logLevel)
 
-	var authimpl mcrouterdiscovery.Auth
-	switch cfg.AuthType {
-	case mcrouterdiscovery.AuthTypeApiKey:
-		authimpl = auth.NewApiKeyAuth(cfg.AuthToken)
-	default:
-		authimpl = auth.NewNoneAuth()
-	}
+	// Each side gets its own credentials so neither secret is sent to the
+	// other service, the server list's are created in newServerList.
+	mcRouterAuth := newAuth(cfg.McRouterAuthType, cfg.McRouterAuthToken)
 
-	sl := mcrouterdiscovery.NewServerListClient(cfg.ServerListAPI, authimpl)
-	mr := mcrouterdiscovery.NewMcRouterClient(cfg.McRouterHost, mcrouterdiscovery.McRouterClientOpts{Auth: authimpl})
-	reconciler := mcrouterdiscovery.NewReconciler(sl, mr, cfg.SyncInterval)
+	retryBudget := mcrouterdiscovery.NewRetryBudget(cfg.RetryBudget)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	go mcrouterdiscovery.StartHealthServer(ctx)
+	// stopSources is swapped by reloads, which run on another goroutine.
+	var sourcesMu sync.Mutex
+	sourcesCtx, stopSources := context.WithCancel(ctx)
+	defer func() {
+		sourcesMu.Lock()
+		defer sourcesMu.Unlock()
+		stopSources()
+	}()
+
+	sl, err := newServerList(sourcesCtx, cfg, retryBudget)
+	if err != nil {
+		log.Fatalf("Failed to configure server list: %s", err)
+	}
+
+	var webhook *mcrouterdiscovery.WebhookServerList
+	var webhookHandler http.Handler
+	if cfg.Webhook {
+		webhook = mcrouterdiscovery.NewWebhookServerList(sl, cfg.WebhookSecret, cfg.WebhookResync)
+		go webhook.Watch(ctx)
+		sl = webhook
+		webhookHandler = webhook
+	}
+	mr := mcrouterdiscovery.NewMcRouterClient(cfg.McRouterHost, mcrouterdiscovery.McRouterClientOpts{
+		Auth:        mcRouterAuth,
+		Retry:       cfg.Retry,
+		RetryBudget: retryBudget,
+	})
+	reconciler := mcrouterdiscovery.NewReconcilerContext(sl, mr, cfg.SyncInterval)
+	reconciler.RetryBudget = retryBudget
+	reconciler.Metrics = mcrouterdiscovery.NewMetrics(registry)
+	reconciler.DryRun = cfg.DryRun
+	reconciler.PruneUnmanaged = cfg.PruneUnmanaged
+	reconciler.DeletionGuard = cfg.DeletionGuard
+	reconciler.DuplicatePolicy = cfg.DuplicatePolicy
+	reconciler.DeleteOrder = cfg.DeleteOrder
+	reconciler.PriorityRoutes = cfg.PriorityRoutes
+	reconciler.ApplyConcurrency = cfg.ApplyConcurrency
+	reconciler.ApplyRateLimit = cfg.ApplyRateLimit
+	if cfg.StateFile != "" {
+		reconciler.Ownership = mcrouterdiscovery.NewFileOwnershipStore(cfg.StateFile)
+	} else {
+		reconciler.Ownership = mcrouterdiscovery.NewMemoryOwnershipStore()
+	}
+	if cfg.HealthCheck {
+		pinger := mcrouterdiscovery.SLPPinger{Timeout: cfg.HealthTimeout}
+		reconciler.HealthChecker = mcrouterdiscovery.NewHealthChecker(pinger, cfg.HealthSuccesses)
+		reconciler.RemoveUnhealthy = cfg.RemoveUnhealthy
+	}
+	reconciler.FallbackBackend = cfg.FallbackBackend
+	reconciler.FallbackGracePeriod = cfg.FallbackGrace
+	reconciler.Debounce = cfg.TriggerDebounce
+
+	// Reloads swap the sources under the webhook and reconciler, which keep
+	// their state, and stop the watchers of the old sources.
+	go watchConfig(ctx, cfg, func(next *mcrouterdiscovery.ParsedConfig) error {
+		nextCtx, stopNext := context.WithCancel(ctx)
+		nextList, err := newServerList(nextCtx, next, retryBudget)
+		if err != nil {
+			stopNext()
+			return err
+		}
+
+		if nextList == nil {
+			stopNext()
+			return errors.New("no server list source is configured")
+		}
+
+		if webhook != nil {
+			webhook.SetUpstream(nextList)
+			nextList = webhook
+		}
+		// The guard and dry run are set first so they already cover the
+		// reconcile triggered by the new server list.
+		reconciler.SetDeletionGuard(next.DeletionGuard)
+		reconciler.SetDryRun(next.DryRun)
+		if err := reconciler.Reload(nextList, next.SyncInterval); err != nil {
+			stopNext()
+			return err
+		}
+		logLevel.Set(next.LogLevel)
+
+		sourcesMu.Lock()
+		defer sourcesMu.Unlock()
+		stopSources()
+		stopSources = stopNext
+		return nil
+	})
+
+	go mcrouterdiscovery.StartHealthServer(ctx, mcrouterdiscovery.HealthServerOpts{
+		Reconciler:      reconciler,
+		StalenessWindow: cfg.ReadinessStale,
+		Gatherer:        registry,
+		TriggerToken:    cfg.TriggerToken,
+		Webhook:         webhookHandler,
+	})
 	reconciler.Start(ctx)
 }
 
-func configureLogger(l slog.Level) {
+// newServerList creates the server lists enabled in cfg, merging them when
+// there are several. Their watchers run until ctx is done. It returns nil if
+// no source is enabled.
+func newServerList(ctx context.Context, cfg *mcrouterdiscovery.ParsedConfig, retryBudget *mcrouterdiscovery.RetryBudget) (mcrouterdiscovery.ContextServerList, error) {
+	var sources []mcrouterdiscovery.ServerListSource
+	if cfg.ServerListFile != "" {
+		fileList := mcrouterdiscovery.NewFileServerList(cfg.ServerListFile, mcrouterdiscovery.DefaultFilePollInterval)
+		go fileList.Watch(ctx)
+		sources = append(sources, mcrouterdiscovery.ServerListSource{Name: "file", ServerList: fileList})
+	}
+	if cfg.ServerListAPI != "" {
+		apiList := mcrouterdiscovery.NewServerListClientWithOpts(cfg.ServerListAPI, newAuth(cfg.AuthType, cfg.AuthToken), mcrouterdiscovery.ServerListClientOpts{
+			Retry:       cfg.Retry,
+			RetryBudget: retryBudget,
+		})
+		sources = append(sources, mcrouterdiscovery.ServerListSource{Name: "api", ServerList: apiList})
+	}
+	if cfg.Kubernetes {
+		opts, err := kubernetesOpts(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure Kubernetes client: %w", err)
+		}
+		k8sList := mcrouterdiscovery.NewKubernetesServerList(opts)
+		go k8sList.Watch(ctx)
+		sources = append(sources, mcrouterdiscovery.ServerListSource{Name: "kubernetes", ServerList: k8sList})
+	}
+	if cfg.Docker {
+		dockerList, err := mcrouterdiscovery.NewDockerServerList(mcrouterdiscovery.DockerServerListOpts{
+			Host:    cfg.DockerHost,
+			Network: cfg.DockerNetwork,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure Docker client: %w", err)
+		}
+		go dockerList.Watch(ctx)
+		sources = append(sources, mcrouterdiscovery.ServerListSource{Name: "docker", ServerList: dockerList})
+	}
+
+	switch len(sources) {
+	case 0:
+		return nil, nil
+	case 1:
+		return sources[0].ServerList, nil
+	default:
+		multi := mcrouterdiscovery.NewMultiServerList(cfg.ConflictPolicy, prioritizeSources(sources, cfg.SourcePriority)...)
+		go multi.Watch(ctx)
+		return multi, nil
+	}
+}
+
+// prioritizeSources orders sources as listed in priority and gives earlier
+// ones a higher Priority. Sources missing from priority go last.
+func prioritizeSources(sources []mcrouterdiscovery.ServerListSource, priority []string) []mcrouterdiscovery.ServerListSource {
+	rank := func(name string) int {
+		if i := slices.Index(priority, name); i >= 0 {
+			return i
+		}
+		return len(priority)
+	}
+
+	slices.SortStableFunc(sources, func(a, b mcrouterdiscovery.ServerListSource) int {
+		return rank(a.Name) - rank(b.Name)
+	})
+	for i := range sources {
+		sources[i].Priority = len(priority) - rank(sources[i].Name)
+	}
+	return sources
+}
+
+func kubernetesOpts(cfg *mcrouterdiscovery.ParsedConfig) (mcrouterdiscovery.KubernetesServerListOpts, error) {
+	opts := mcrouterdiscovery.KubernetesServerListOpts{APIServer: cfg.KubernetesAPI}
+	if cfg.KubernetesAPI == "" {
+		var err error
+		opts, err = mcrouterdiscovery.InClusterKubernetesOpts()
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	opts.Namespace = cfg.KubernetesNS
+	opts.LabelSelector = cfg.KubernetesLabels
+	opts.Pods = cfg.KubernetesPods
+	return opts, nil
+}
+
+func newAuth(t mcrouterdiscovery.AuthType, token string) mcrouterdiscovery.Auth {
+	switch t {
+	case mcrouterdiscovery.AuthTypeApiKey:
+		return auth.NewApiKeyAuth(token)
+	default:
+		return auth.NewNoneAuth()
+	}
+}
+
+func configureLogger(l slog.Leveler) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: l,
 	}))
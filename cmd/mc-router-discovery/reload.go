@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"syscall"
+
+	mcrouterdiscovery "github.com/Seedloaf/mc-router-discovery"
+)
+
+// reloadableFields are the ParsedConfig fields applied by a reload, changes
+// to any other field need a restart.
+var reloadableFields = []string{
+	"ServerListAPI", "ServerListFile",
+	"Kubernetes", "KubernetesAPI", "KubernetesNS", "KubernetesLabels", "KubernetesPods",
+	"Docker", "DockerHost", "DockerNetwork",
+	"AuthType", "AuthToken", "ConflictPolicy", "SourcePriority",
+	"SyncInterval", "LogLevel", "DeletionGuard", "DryRun",
+}
+
+// watchConfig reloads the config on SIGHUP and, when a config file is used,
+// whenever it changes. A config that fails to load or apply is logged and
+// the running one is kept.
+func watchConfig(ctx context.Context, running *mcrouterdiscovery.ParsedConfig, apply func(*mcrouterdiscovery.ParsedConfig) error) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var fileChanges <-chan struct{}
+	if running.ConfigFile != "" {
+		fileChanges = mcrouterdiscovery.WatchConfigFile(ctx, running.ConfigFile)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("Received SIGHUP, reloading config")
+		case <-fileChanges:
+		}
+
+		next, err := mcrouterdiscovery.LoadConfigArgs(os.Args[1:])
+		if err != nil {
+			slog.Error("Failed to reload config, keeping the running config", "err", err)
+			continue
+		}
+		if err := apply(next); err != nil {
+			slog.Error("Failed to apply reloaded config, keeping the running config", "err", err)
+			continue
+		}
+
+		if fields := pendingRestart(running, next); len(fields) > 0 {
+			slog.Warn("Some config changes need a restart to apply", "fields", fields)
+		}
+		slog.Info("Reloaded config", "syncInterval", next.SyncInterval)
+	}
+}
+
+// pendingRestart returns the fields that differ between running and next but
+// are not applied by a reload.
+func pendingRestart(running, next *mcrouterdiscovery.ParsedConfig) []string {
+	a, b := reflect.ValueOf(running).Elem(), reflect.ValueOf(next).Elem()
+
+	var fields []string
+	for i := range a.NumField() {
+		name := a.Type().Field(i).Name
+		if slices.Contains(reloadableFields, name) {
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
@@ -1,55 +1,241 @@
 package mcrouterdiscovery
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator"
+	"gopkg.in/yaml.v3"
 )
 
 var (
 	ErrMissingRequired = errors.New("missing required argument")
 )
 
+// EnvPrefix is prepended to the environment variables that set flags and
+// secrets, see EnvName.
+const EnvPrefix = "MC_ROUTER_SYNC_"
+
+// flagAliases maps deprecated flags to the flag they set. Both count as the
+// same setting when deciding whether a flag, the environment or the config
+// file wins.
+var flagAliases = map[string]string{
+	"auth-type": "server-list-auth-type",
+}
+
+func canonicalFlag(name string) string {
+	if canonical, ok := flagAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// ServerListSourceNames are the names of the sources that can be enabled on
+// the command line, in their default priority order.
+var ServerListSourceNames = []string{"file", "api", "kubernetes", "docker"}
+
 type Config struct {
-	McRouterHost  string `validate:"required"`
-	ServerListAPI string `validate:"required"`
-	AuthType      string // "apikey", "none"
-	AuthToken     string // Bearer token or API key value
-	LogLevel      string
-	SyncInterval  int // Sync interval in seconds
+	ConfigFile        string
+	McRouterHost      string `validate:"required"`
+	ServerListAPI     string
+	ServerListFile    string
+	Kubernetes        bool
+	KubernetesAPI     string
+	KubernetesNS      string
+	KubernetesLabels  string
+	KubernetesPods    bool
+	Docker            bool
+	DockerHost        string
+	DockerNetwork     string
+	AuthType          string // "apikey", "none", for the server list API
+	AuthToken         string // Bearer token or API key value for the server list API
+	McRouterAuthType  string // "apikey", "none"
+	McRouterAuthToken string // Bearer token or API key value for mc-router
+	LogLevel          string
+	SyncInterval      int // Sync interval in seconds
+	DryRun            bool
+	StateFile         string
+	PruneUnmanaged    bool
+	MaxDeletes        int
+	MaxDeletePercent  float64
+	ForceDeletes      bool
+	RetryAttempts     int
+	RetryBackoff      int // Initial retry backoff in milliseconds
+	RetryMaxBackoff   int // Maximum retry backoff in milliseconds
+	RetryBudget       int // Maximum retries per sync
+	ReadinessStale    int // Seconds since the last successful sync before readiness fails
+	HealthCheck       bool
+	HealthSuccesses   int // Consecutive successful pings before a backend is registered
+	HealthTimeout     int // Ping timeout in milliseconds
+	RemoveUnhealthy   bool
+	FallbackBackend   string
+	FallbackGrace     int // Seconds a missing route points at the fallback before it is deleted
+	TriggerToken      string
+	TriggerDebounce   int // Milliseconds to wait after a trigger before reconciling
+	Webhook           bool
+	WebhookSecret     string
+	WebhookResync     int // Seconds between upstream resyncs when the webhook is enabled
+	ConflictPolicy    string
+	SourcePriority    string // Comma separated source names, highest priority first
+	DuplicatePolicy   string
+	DeleteOrder       string
+	PriorityRoutes    string // Comma separated server address patterns applied first
+	ApplyConcurrency  int
+	ApplyRateLimit    float64 // Actions per second
 }
 
 type ParsedConfig struct {
-	McRouterHost  string
-	ServerListAPI string
-	AuthType      AuthType
-	AuthToken     string
-	LogLevel      slog.Level
-	SyncInterval  time.Duration
+	ConfigFile        string
+	McRouterHost      string
+	ServerListAPI     string
+	ServerListFile    string
+	Kubernetes        bool
+	KubernetesAPI     string
+	KubernetesNS      string
+	KubernetesLabels  string
+	KubernetesPods    bool
+	Docker            bool
+	DockerHost        string
+	DockerNetwork     string
+	AuthType          AuthType
+	AuthToken         string
+	McRouterAuthType  AuthType
+	McRouterAuthToken string
+	LogLevel          slog.Level
+	SyncInterval      time.Duration
+	DryRun            bool
+	StateFile         string
+	PruneUnmanaged    bool
+	DeletionGuard     DeletionGuard
+	Retry             RetryPolicy
+	RetryBudget       int
+	ReadinessStale    time.Duration
+	HealthCheck       bool
+	HealthSuccesses   int
+	HealthTimeout     time.Duration
+	RemoveUnhealthy   bool
+	FallbackBackend   string
+	FallbackGrace     time.Duration
+	TriggerToken      string
+	TriggerDebounce   time.Duration
+	Webhook           bool
+	WebhookSecret     string
+	WebhookResync     time.Duration
+	ConflictPolicy    ConflictPolicy
+	SourcePriority    []string
+	DuplicatePolicy   DuplicatePolicy
+	DeleteOrder       DeleteOrder
+	PriorityRoutes    []string
+	ApplyConcurrency  int
+	ApplyRateLimit    float64
 }
 
 func LoadConfigFromFlags() (*ParsedConfig, error) {
+	return loadConfig(flag.CommandLine, os.Args[1:])
+}
+
+// LoadConfigArgs parses args, without the program name, the same way as
+// LoadConfigFromFlags, re-reading the config file and environment. It is
+// used to reload the config of a running process.
+func LoadConfigArgs(args []string) (*ParsedConfig, error) {
+	return loadConfig(flag.NewFlagSet("mc-router-discovery", flag.ContinueOnError), args)
+}
+
+func loadConfig(fs *flag.FlagSet, args []string) (*ParsedConfig, error) {
 	v := validator.New()
 
 	config := &Config{}
 
-	flag.StringVar(&config.McRouterHost, "mc-router-host", "", "* McRouter API host (e.g. http://localhost:8000)")
-	flag.StringVar(&config.ServerListAPI, "server-list-api", "", "* Server list API endpoint (e.g. http://localhost:3000/api/servers)")
-	flag.StringVar(&config.AuthType, "auth-type", "none", "Authentication type for the server list API: apikey, none")
-	flag.StringVar(&config.LogLevel, "log-level", "info", "The lowest level log you would like (e.g. debug)")
-	flag.IntVar(&config.SyncInterval, "sync-interval", 30, "Sync interval in seconds")
+	fs.StringVar(&config.ConfigFile, "config", "", "YAML config file whose keys are flag names, overridden by flags and environment variables")
+	fs.StringVar(&config.McRouterHost, "mc-router-host", "", "* McRouter API host (e.g. http://localhost:8000)")
+	fs.StringVar(&config.ServerListAPI, "server-list-api", "", "* Server list API endpoint (e.g. http://localhost:3000/api/servers), unless another source is set")
+	fs.StringVar(&config.ServerListFile, "server-list-file", "", "Local JSON or YAML file to read routes from, reloaded on change")
+	fs.BoolVar(&config.Kubernetes, "kubernetes", false, "Discover routes from annotated Kubernetes Services")
+	fs.StringVar(&config.KubernetesAPI, "kubernetes-api", "", "Kubernetes API server URL, e.g. from kubectl proxy (default: in-cluster config)")
+	fs.StringVar(&config.KubernetesNS, "kubernetes-namespace", "", "Only discover objects in this namespace (default: all namespaces)")
+	fs.StringVar(&config.KubernetesLabels, "kubernetes-label-selector", "", "Only discover objects matching this label selector")
+	fs.BoolVar(&config.KubernetesPods, "kubernetes-pods", false, "Also discover annotated Pods, routing to the pod IP")
+	fs.BoolVar(&config.Docker, "docker", false, "Discover routes from labeled Docker containers")
+	fs.StringVar(&config.DockerHost, "docker-host", "", "Docker Engine address, unix:// or tcp:// (default: DOCKER_HOST or unix:///var/run/docker.sock)")
+	fs.StringVar(&config.DockerNetwork, "docker-network", "", "Network whose container IP is used for backends (default: the first network with an IP)")
+	fs.StringVar(&config.AuthType, "server-list-auth-type", "none", "Authentication type for the server list API: apikey, none")
+	fs.StringVar(&config.AuthType, "auth-type", "none", "Deprecated alias for server-list-auth-type")
+	fs.StringVar(&config.McRouterAuthType, "mc-router-auth-type", "none", "Authentication type for the mc-router API: apikey, none")
+	fs.StringVar(&config.LogLevel, "log-level", "info", "The lowest level log you would like (e.g. debug)")
+	fs.IntVar(&config.SyncInterval, "sync-interval", 30, "Sync interval in seconds")
+	fs.BoolVar(&config.DryRun, "dry-run", false, "Log the planned changes to mc-router without applying them")
+	fs.StringVar(&config.StateFile, "state-file", "", "File used to remember which routes this service registered (default: in memory)")
+	fs.BoolVar(&config.PruneUnmanaged, "prune-unmanaged", false, "Also delete mc-router routes that were not registered by this service")
+	fs.IntVar(&config.MaxDeletes, "max-deletes", 0, "Refuse to apply a sync that deletes more than this many routes (0 disables)")
+	fs.Float64Var(&config.MaxDeletePercent, "max-delete-percent", 0, "Refuse to apply a sync that deletes more than this percentage of mc-router's routes (0 disables)")
+	fs.BoolVar(&config.ForceDeletes, "force-deletes", false, "Apply syncs even when they exceed the delete limits")
+	fs.IntVar(&config.RetryAttempts, "retry-attempts", 3, "Attempts per request to mc-router and the server list API (1 disables retries)")
+	fs.IntVar(&config.RetryBackoff, "retry-backoff", 250, "Initial retry backoff in milliseconds, doubled after each attempt")
+	fs.IntVar(&config.RetryMaxBackoff, "retry-max-backoff", 5000, "Maximum retry backoff in milliseconds")
+	fs.IntVar(&config.RetryBudget, "retry-budget", 20, "Maximum number of retries across all requests in a single sync")
+	fs.IntVar(&config.ReadinessStale, "readiness-staleness", 0, "Seconds since the last successful sync before /ready fails (default: 3 sync intervals)")
+	fs.BoolVar(&config.HealthCheck, "health-check", false, "Ping backends with the Minecraft Server List Ping before registering routes to them")
+	fs.IntVar(&config.HealthSuccesses, "health-check-successes", 2, "Consecutive successful pings, one per sync, before a backend is registered")
+	fs.IntVar(&config.HealthTimeout, "health-check-timeout", 3000, "Ping timeout in milliseconds")
+	fs.BoolVar(&config.RemoveUnhealthy, "remove-unhealthy", false, "Delete routes whose backend stops answering pings, requires health-check")
+	fs.StringVar(&config.FallbackBackend, "fallback-backend", "", "Maintenance backend that routes point at while their backend is unhealthy or missing from the server list")
+	fs.IntVar(&config.FallbackGrace, "fallback-grace-period", 300, "Seconds a route missing from the server list points at the fallback backend before it is deleted (0 deletes immediately)")
+	fs.StringVar(&config.ConflictPolicy, "conflict-policy", string(ConflictFirstWins), "How to resolve a server address claimed by several sources: first-wins, priority, fail")
+	fs.StringVar(&config.SourcePriority, "source-priority", strings.Join(ServerListSourceNames, ","), "Order of the sources for the conflict policy, first wins")
+	fs.StringVar(&config.DuplicatePolicy, "duplicate-policy", string(DuplicateKeepLast), "What to do when the server list has a server address several times: keep-last, keep-first, error")
+	fs.StringVar(&config.DeleteOrder, "delete-order", string(DeletesLast), "Apply deletes before or after adds and updates: first, last")
+	fs.StringVar(&config.PriorityRoutes, "priority-routes", "", "Comma separated server address patterns, e.g. lobby.*, whose changes are applied first")
+	fs.IntVar(&config.ApplyConcurrency, "apply-concurrency", 8, "Number of changes sent to mc-router at once (1 applies them one by one)")
+	fs.Float64Var(&config.ApplyRateLimit, "apply-rate-limit", 0, "Maximum changes sent to mc-router per second (0 disables)")
+	fs.BoolVar(&config.Webhook, "webhook", false, "Accept signed route changes on POST /webhook, on top of the configured server list")
+	fs.IntVar(&config.WebhookResync, "webhook-resync-interval", 300, "Seconds between full resyncs of the server list when the webhook is enabled")
+	fs.IntVar(&config.TriggerDebounce, "trigger-debounce", 250, "Milliseconds to wait after a change or POST /reconcile before syncing, so bursts result in one sync")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
 
-	flag.Parse()
+	// Flags take precedence over the environment, which takes precedence
+	// over the config file.
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[canonicalFlag(f.Name)] = true
+	})
 
-	config.AuthToken = resolveApiKeySecrets()
+	if err := applyEnv(fs, set); err != nil {
+		return nil, err
+	}
+
+	if config.ConfigFile != "" {
+		if err := applyConfigFile(fs, config.ConfigFile, set); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if config.AuthToken, err = resolveSecret("SERVER_LIST_API_KEY", "API_KEY"); err != nil {
+		return nil, err
+	}
+	if config.McRouterAuthToken, err = resolveSecret("MC_ROUTER_API_KEY"); err != nil {
+		return nil, err
+	}
+	if config.TriggerToken, err = resolveSecret("RECONCILE_TRIGGER_TOKEN"); err != nil {
+		return nil, err
+	}
+	if config.WebhookSecret, err = resolveSecret("WEBHOOK_SECRET"); err != nil {
+		return nil, err
+	}
 
 	var validateErrs validator.ValidationErrors
-	err := v.Struct(config)
+	err = v.Struct(config)
 	if err != nil {
 		if errors.As(err, &validateErrs) {
 			for _, err := range validateErrs {
@@ -60,6 +246,57 @@ func LoadConfigFromFlags() (*ParsedConfig, error) {
 		return nil, fmt.Errorf("failed to validate config: %w", err)
 	}
 
+	sources := 0
+	for _, set := range []bool{config.ServerListAPI != "", config.ServerListFile != "", config.Kubernetes, config.Docker} {
+		if set {
+			sources++
+		}
+	}
+
+	if config.DockerHost == "" {
+		config.DockerHost = os.Getenv("DOCKER_HOST")
+	}
+
+	// The webhook needs an upstream too: pushed routes are only kept in
+	// memory, so after a restart they would all be deleted.
+	if sources == 0 {
+		return nil, fmt.Errorf("server-list-api, server-list-file, kubernetes or docker is required")
+	}
+
+	conflictPolicy, err := GetConflictPolicy(config.ConflictPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	var sourcePriority []string
+	for _, name := range strings.Split(config.SourcePriority, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(ServerListSourceNames, name) {
+			return nil, fmt.Errorf("invalid source in source-priority: %s (must be one of %s)", name, strings.Join(ServerListSourceNames, ", "))
+		}
+		sourcePriority = append(sourcePriority, name)
+	}
+
+	duplicatePolicy, err := GetDuplicatePolicy(config.DuplicatePolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteOrder, err := GetDeleteOrder(config.DeleteOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	var priorityRoutes []string
+	for _, pattern := range strings.Split(config.PriorityRoutes, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			priorityRoutes = append(priorityRoutes, pattern)
+		}
+	}
+	if err := ValidatePriorityRoutes(priorityRoutes); err != nil {
+		return nil, err
+	}
+
 	authType, err := GetAuthType(config.AuthType)
 	if err != nil {
 		return nil, fmt.Errorf("invalid auth-type: %s (must be apikey or none)", config.AuthType)
@@ -69,16 +306,241 @@ func LoadConfigFromFlags() (*ParsedConfig, error) {
 		return nil, fmt.Errorf("auth-token is required when auth-type is %s", config.AuthType)
 	}
 
+	mcRouterAuthType, err := GetAuthType(config.McRouterAuthType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mc-router-auth-type: %s (must be apikey or none)", config.McRouterAuthType)
+	}
+
+	if mcRouterAuthType == AuthTypeApiKey && config.McRouterAuthToken == "" {
+		return nil, fmt.Errorf("MC_ROUTER_API_KEY is required when mc-router-auth-type is %s", config.McRouterAuthType)
+	}
+
+	if config.MaxDeletes < 0 {
+		return nil, fmt.Errorf("max-deletes must not be negative")
+	}
+
+	if config.MaxDeletePercent < 0 || config.MaxDeletePercent > 100 {
+		return nil, fmt.Errorf("max-delete-percent must be between 0 and 100")
+	}
+
+	if config.SyncInterval < 1 {
+		return nil, fmt.Errorf("sync-interval must be at least 1")
+	}
+
+	if config.ReadinessStale < 0 {
+		return nil, fmt.Errorf("readiness-staleness must not be negative")
+	}
+
+	readinessStale := time.Duration(config.ReadinessStale) * time.Second
+	if readinessStale == 0 {
+		readinessStale = 3 * time.Duration(config.SyncInterval) * time.Second
+	}
+
+	if config.RetryAttempts < 1 {
+		return nil, fmt.Errorf("retry-attempts must be at least 1")
+	}
+
+	if config.RetryBackoff < 0 || config.RetryMaxBackoff < 0 || config.RetryBudget < 0 {
+		return nil, fmt.Errorf("retry-backoff, retry-max-backoff and retry-budget must not be negative")
+	}
+
+	if config.HealthSuccesses < 1 {
+		return nil, fmt.Errorf("health-check-successes must be at least 1")
+	}
+
+	if config.HealthTimeout <= 0 {
+		return nil, fmt.Errorf("health-check-timeout must be positive")
+	}
+
+	if config.RemoveUnhealthy && !config.HealthCheck {
+		return nil, fmt.Errorf("remove-unhealthy requires health-check")
+	}
+
+	if config.Webhook && config.WebhookSecret == "" {
+		return nil, fmt.Errorf("WEBHOOK_SECRET is required when webhook is enabled")
+	}
+
+	if config.WebhookResync <= 0 {
+		return nil, fmt.Errorf("webhook-resync-interval must be positive")
+	}
+
+	if config.ApplyConcurrency < 1 {
+		return nil, fmt.Errorf("apply-concurrency must be at least 1")
+	}
+
+	if config.ApplyRateLimit < 0 {
+		return nil, fmt.Errorf("apply-rate-limit must not be negative")
+	}
+
+	if config.TriggerDebounce < 0 {
+		return nil, fmt.Errorf("trigger-debounce must not be negative")
+	}
+
+	if config.FallbackGrace < 0 {
+		return nil, fmt.Errorf("fallback-grace-period must not be negative")
+	}
+
+	// Desired backends are normalized, so the fallback must be too for
+	// routes to be recognized as pointing at it.
+	if config.FallbackBackend != "" {
+		fallbackBackend, err := NormalizeBackend(config.FallbackBackend)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fallback-backend: %w", err)
+		}
+		config.FallbackBackend = fallbackBackend
+	}
+
+	retry := DefaultRetryPolicy()
+	retry.MaxAttempts = config.RetryAttempts
+	retry.InitialBackoff = time.Duration(config.RetryBackoff) * time.Millisecond
+	retry.MaxBackoff = time.Duration(config.RetryMaxBackoff) * time.Millisecond
+
 	return &ParsedConfig{
-		McRouterHost:  config.McRouterHost,
-		ServerListAPI: config.ServerListAPI,
-		AuthType:      authType,
-		AuthToken:     config.AuthToken,
-		LogLevel:      resolveLogLevel(config.LogLevel),
-		SyncInterval:  time.Duration(config.SyncInterval) * time.Second,
+		ConfigFile:        config.ConfigFile,
+		McRouterHost:      config.McRouterHost,
+		ServerListAPI:     config.ServerListAPI,
+		ServerListFile:    config.ServerListFile,
+		Kubernetes:        config.Kubernetes,
+		KubernetesAPI:     config.KubernetesAPI,
+		KubernetesNS:      config.KubernetesNS,
+		KubernetesLabels:  config.KubernetesLabels,
+		KubernetesPods:    config.KubernetesPods,
+		Docker:            config.Docker,
+		DockerHost:        config.DockerHost,
+		DockerNetwork:     config.DockerNetwork,
+		AuthType:          authType,
+		AuthToken:         config.AuthToken,
+		McRouterAuthType:  mcRouterAuthType,
+		McRouterAuthToken: config.McRouterAuthToken,
+		LogLevel:          resolveLogLevel(config.LogLevel),
+		SyncInterval:      time.Duration(config.SyncInterval) * time.Second,
+		DryRun:            config.DryRun,
+		StateFile:         config.StateFile,
+		PruneUnmanaged:    config.PruneUnmanaged,
+		DeletionGuard: DeletionGuard{
+			MaxDeletes:       config.MaxDeletes,
+			MaxDeletePercent: config.MaxDeletePercent,
+			Override:         config.ForceDeletes,
+		},
+		Retry:            retry,
+		RetryBudget:      config.RetryBudget,
+		ReadinessStale:   readinessStale,
+		HealthCheck:      config.HealthCheck,
+		HealthSuccesses:  config.HealthSuccesses,
+		HealthTimeout:    time.Duration(config.HealthTimeout) * time.Millisecond,
+		RemoveUnhealthy:  config.RemoveUnhealthy,
+		FallbackBackend:  config.FallbackBackend,
+		FallbackGrace:    time.Duration(config.FallbackGrace) * time.Second,
+		TriggerToken:     config.TriggerToken,
+		TriggerDebounce:  time.Duration(config.TriggerDebounce) * time.Millisecond,
+		Webhook:          config.Webhook,
+		WebhookSecret:    config.WebhookSecret,
+		WebhookResync:    time.Duration(config.WebhookResync) * time.Second,
+		ConflictPolicy:   conflictPolicy,
+		SourcePriority:   sourcePriority,
+		DuplicatePolicy:  duplicatePolicy,
+		DeleteOrder:      deleteOrder,
+		PriorityRoutes:   priorityRoutes,
+		ApplyConcurrency: config.ApplyConcurrency,
+		ApplyRateLimit:   config.ApplyRateLimit,
 	}, nil
 }
 
+// WatchConfigFile signals the returned channel whenever the contents of the
+// config file at path change, until ctx is done.
+func WatchConfigFile(ctx context.Context, path string) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	go pollFile(ctx, path, DefaultFilePollInterval, func() {
+		slog.Info("Config file changed", "path", path)
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+	return changes
+}
+
+// EnvName returns the environment variable that sets the flag name, e.g.
+// MC_ROUTER_SYNC_MC_ROUTER_HOST for mc-router-host.
+func EnvName(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnv sets every flag whose EnvName is set in the environment, except
+// those in set, and adds them to set.
+func applyEnv(fs *flag.FlagSet, set map[string]bool) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(EnvName(f.Name))
+		if !ok || set[canonicalFlag(f.Name)] || err != nil {
+			return
+		}
+		// The canonical variable wins over its alias.
+		if canonical := canonicalFlag(f.Name); canonical != f.Name {
+			if _, ok := os.LookupEnv(EnvName(canonical)); ok {
+				return
+			}
+		}
+
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("invalid %s: %w", EnvName(f.Name), setErr)
+			return
+		}
+		set[canonicalFlag(f.Name)] = true
+	})
+
+	return err
+}
+
+// applyConfigFile sets the flags named by the keys of the YAML file at path,
+// except those in set. Lists are joined with commas, e.g. for
+// source-priority.
+func applyConfigFile(fs *flag.FlagSet, path string, set map[string]bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		f := fs.Lookup(name)
+		if f == nil || name == "config" {
+			return fmt.Errorf("unknown key in config file: %s", name)
+		}
+		if set[canonicalFlag(name)] {
+			continue
+		}
+		// The canonical key wins over its alias.
+		if canonical := canonicalFlag(name); canonical != name {
+			if _, ok := values[canonical]; ok {
+				continue
+			}
+		}
+
+		value := fmt.Sprint(values[name])
+		if values[name] == nil {
+			value = ""
+		}
+		if list, ok := values[name].([]any); ok {
+			items := make([]string, len(list))
+			for i, item := range list {
+				items[i] = fmt.Sprint(item)
+			}
+			value = strings.Join(items, ",")
+		}
+
+		if err := f.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid %s in config file: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
 func resolveLogLevel(l string) slog.Level {
 	switch l {
 	case "debug":
@@ -94,6 +556,31 @@ func resolveLogLevel(l string) slog.Level {
 	}
 }
 
-func resolveApiKeySecrets() string {
-	return os.Getenv("API_KEY")
+// resolveSecret returns the first secret set among names, each of which is
+// read from EnvPrefix+name, name, or the file named by either with a _FILE
+// suffix, in that order. Earlier names take precedence, e.g. the newer
+// SERVER_LIST_API_KEY over API_KEY.
+func resolveSecret(names ...string) (string, error) {
+	for _, name := range names {
+		for _, key := range []string{EnvPrefix + name, name} {
+			value := os.Getenv(key)
+			path := os.Getenv(key + "_FILE")
+			if value != "" && path != "" {
+				return "", fmt.Errorf("only one of %s and %s_FILE can be set", key, key)
+			}
+			if value != "" {
+				return value, nil
+			}
+			if path != "" {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return "", fmt.Errorf("failed to read %s_FILE: %w", key, err)
+				}
+				// Secret files usually end in a newline that isn't part of the secret.
+				return strings.TrimRight(string(data), "\r\n"), nil
+			}
+		}
+	}
+
+	return "", nil
 }
@@ -2,7 +2,9 @@ package mcrouterdiscovery
 
 import (
 	"flag"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -23,6 +25,7 @@ func TestLoadConfigFromFlags(t *testing.T) {
 		name        string
 		args        []string
 		envAPIKey   string
+		env         map[string]string
 		expectError bool
 		errorMsg    string
 		validate    func(*testing.T, *ParsedConfig)
@@ -37,7 +40,212 @@ func TestLoadConfigFromFlags(t *testing.T) {
 			name:        "missing server-list-api",
 			args:        []string{"cmd", "-mc-router-host=http://localhost:8080"},
 			expectError: true,
-			errorMsg:    "ServerListAPI is invalid: required",
+			errorMsg:    "server-list-api, server-list-file, kubernetes or docker is required",
+		},
+		{
+			name: "env vars",
+			args: []string{"cmd"},
+			env: map[string]string{
+				"MC_ROUTER_SYNC_MC_ROUTER_HOST":   "http://localhost:8080",
+				"MC_ROUTER_SYNC_SERVER_LIST_API":  "http://api.example.com",
+				"MC_ROUTER_SYNC_DRY_RUN":          "true",
+				"MC_ROUTER_SYNC_SOURCE_PRIORITY":  "api,file",
+				"MC_ROUTER_SYNC_SERVER_LIST_FILE": "routes.json",
+			},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.McRouterHost != "http://localhost:8080" || c.ServerListAPI != "http://api.example.com" || c.ServerListFile != "routes.json" {
+					t.Errorf("expected the settings from the environment, got %+v", c)
+				}
+				if !c.DryRun {
+					t.Error("expected DryRun to be enabled")
+				}
+				if len(c.SourcePriority) != 2 || c.SourcePriority[0] != "api" {
+					t.Errorf("expected SourcePriority to be [api file], got %v", c.SourcePriority)
+				}
+			},
+		},
+		{
+			name: "flags override env vars",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-sync-interval=60"},
+			env:  map[string]string{"MC_ROUTER_SYNC_SYNC_INTERVAL": "10", "MC_ROUTER_SYNC_LOG_LEVEL": "debug"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.SyncInterval != time.Minute {
+					t.Errorf("expected the flag to override SyncInterval, got %s", c.SyncInterval)
+				}
+				if c.LogLevel != slog.LevelDebug {
+					t.Errorf("expected LogLevel from the environment, got %s", c.LogLevel)
+				}
+			},
+		},
+		{
+			name: "flag overrides the env var of its alias",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-server-list-auth-type=apikey"},
+			env:  map[string]string{"MC_ROUTER_SYNC_AUTH_TYPE": "none", "SERVER_LIST_API_KEY": "list-secret"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.AuthType != AuthTypeApiKey || c.AuthToken != "list-secret" {
+					t.Errorf("expected the apikey auth from the flag, got %s with token %q", c.AuthType, c.AuthToken)
+				}
+			},
+		},
+		{
+			name: "env var overrides its alias",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com"},
+			env:  map[string]string{"MC_ROUTER_SYNC_AUTH_TYPE": "none", "MC_ROUTER_SYNC_SERVER_LIST_AUTH_TYPE": "apikey", "SERVER_LIST_API_KEY": "list-secret"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.AuthType != AuthTypeApiKey {
+					t.Errorf("expected AuthType to be apikey, got %s", c.AuthType)
+				}
+			},
+		},
+		{
+			name:        "invalid env var",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com"},
+			env:         map[string]string{"MC_ROUTER_SYNC_SYNC_INTERVAL": "soon"},
+			expectError: true,
+			errorMsg:    "invalid MC_ROUTER_SYNC_SYNC_INTERVAL: parse error",
+		},
+		{
+			name: "prefixed secret",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-mc-router-auth-type=apikey"},
+			env:  map[string]string{"MC_ROUTER_SYNC_MC_ROUTER_API_KEY": "router-secret"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.McRouterAuthToken != "router-secret" {
+					t.Errorf("expected McRouterAuthToken to be router-secret, got %s", c.McRouterAuthToken)
+				}
+			},
+		},
+		{
+			name: "server list file",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-file=/etc/mc-router-sync/routes.yaml"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.ServerListFile != "/etc/mc-router-sync/routes.yaml" {
+					t.Errorf("expected ServerListFile to be /etc/mc-router-sync/routes.yaml, got %s", c.ServerListFile)
+				}
+			},
+		},
+		{
+			name: "server list api and file",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-server-list-file=routes.json"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.ServerListAPI != "http://api.example.com" || c.ServerListFile != "routes.json" {
+					t.Errorf("expected both sources to be set, got %s and %s", c.ServerListAPI, c.ServerListFile)
+				}
+				if c.ConflictPolicy != ConflictFirstWins {
+					t.Errorf("expected ConflictPolicy to be first-wins, got %s", c.ConflictPolicy)
+				}
+				if len(c.SourcePriority) != len(ServerListSourceNames) {
+					t.Errorf("expected the default source priority, got %v", c.SourcePriority)
+				}
+			},
+		},
+		{
+			name: "kubernetes discovery",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-kubernetes", "-kubernetes-namespace=minecraft", "-kubernetes-pods"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if !c.Kubernetes || !c.KubernetesPods {
+					t.Error("expected Kubernetes and KubernetesPods to be enabled")
+				}
+				if c.KubernetesNS != "minecraft" {
+					t.Errorf("expected KubernetesNS to be minecraft, got %s", c.KubernetesNS)
+				}
+			},
+		},
+		{
+			name: "docker discovery",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-docker", "-docker-network=minecraft"},
+			env:  map[string]string{"DOCKER_HOST": "tcp://docker.example.com:2375"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if !c.Docker {
+					t.Error("expected Docker to be enabled")
+				}
+				if c.DockerHost != "tcp://docker.example.com:2375" {
+					t.Errorf("expected DockerHost from DOCKER_HOST, got %s", c.DockerHost)
+				}
+				if c.DockerNetwork != "minecraft" {
+					t.Errorf("expected DockerNetwork to be minecraft, got %s", c.DockerNetwork)
+				}
+			},
+		},
+		{
+			name: "kubernetes and server list api with priority",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-kubernetes", "-conflict-policy=priority", "-source-priority=kubernetes, api"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.ConflictPolicy != ConflictPriority {
+					t.Errorf("expected ConflictPolicy to be priority, got %s", c.ConflictPolicy)
+				}
+				if len(c.SourcePriority) != 2 || c.SourcePriority[0] != "kubernetes" || c.SourcePriority[1] != "api" {
+					t.Errorf("expected SourcePriority to be [kubernetes api], got %v", c.SourcePriority)
+				}
+			},
+		},
+		{
+			name:        "invalid conflict policy",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-conflict-policy=last-wins"},
+			expectError: true,
+			errorMsg:    "invalid conflict policy: last-wins (must be first-wins, priority or fail)",
+		},
+		{
+			name: "duplicate policy",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-duplicate-policy=error"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.DuplicatePolicy != DuplicateError {
+					t.Errorf("expected DuplicatePolicy to be error, got %s", c.DuplicatePolicy)
+				}
+			},
+		},
+		{
+			name:        "invalid duplicate policy",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-duplicate-policy=merge"},
+			expectError: true,
+			errorMsg:    "invalid duplicate policy: merge (must be keep-last, keep-first or error)",
+		},
+		{
+			name: "plan order",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-delete-order=first", "-priority-routes=lobby.*, *.lobby.example.com"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.DeleteOrder != DeletesFirst {
+					t.Errorf("expected DeleteOrder to be first, got %s", c.DeleteOrder)
+				}
+				if len(c.PriorityRoutes) != 2 || c.PriorityRoutes[1] != "*.lobby.example.com" {
+					t.Errorf("expected PriorityRoutes to be [lobby.* *.lobby.example.com], got %v", c.PriorityRoutes)
+				}
+			},
+		},
+		{
+			name:        "invalid priority route",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-priority-routes=[lobby"},
+			expectError: true,
+			errorMsg:    `invalid priority route pattern "[lobby": syntax error in pattern`,
+		},
+		{
+			name: "apply concurrency and rate limit",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-apply-concurrency=16", "-apply-rate-limit=2.5"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.ApplyConcurrency != 16 {
+					t.Errorf("expected ApplyConcurrency to be 16, got %d", c.ApplyConcurrency)
+				}
+				if c.ApplyRateLimit != 2.5 {
+					t.Errorf("expected ApplyRateLimit to be 2.5, got %v", c.ApplyRateLimit)
+				}
+			},
+		},
+		{
+			name:        "invalid apply concurrency",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-apply-concurrency=0"},
+			expectError: true,
+			errorMsg:    "apply-concurrency must be at least 1",
+		},
+		{
+			name:        "negative apply rate limit",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-apply-rate-limit=-1"},
+			expectError: true,
+			errorMsg:    "apply-rate-limit must not be negative",
+		},
+		{
+			name:        "invalid source priority",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-source-priority=api,consul"},
+			expectError: true,
+			errorMsg:    "invalid source in source-priority: consul (must be one of file, api, kubernetes, docker)",
 		},
 		{
 			name: "valid config with no auth",
@@ -76,6 +284,48 @@ func TestLoadConfigFromFlags(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "separate mc router credentials",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-server-list-auth-type=apikey", "-mc-router-auth-type=apikey"},
+			env: map[string]string{
+				"SERVER_LIST_API_KEY": "server-list-secret",
+				"MC_ROUTER_API_KEY":   "mc-router-secret",
+			},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.AuthType != AuthTypeApiKey || c.AuthToken != "server-list-secret" {
+					t.Errorf("expected server list apikey auth with server-list-secret, got %s %s", c.AuthType, c.AuthToken)
+				}
+				if c.McRouterAuthType != AuthTypeApiKey || c.McRouterAuthToken != "mc-router-secret" {
+					t.Errorf("expected mc router apikey auth with mc-router-secret, got %s %s", c.McRouterAuthType, c.McRouterAuthToken)
+				}
+			},
+		},
+		{
+			name:      "server list credentials are not shared with mc router",
+			args:      []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-auth-type=apikey"},
+			envAPIKey: "secret123",
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.McRouterAuthType != AuthTypeNone {
+					t.Errorf("expected mc router auth to default to none, got %s", c.McRouterAuthType)
+				}
+				if c.McRouterAuthToken != "" {
+					t.Errorf("expected no mc router token, got %s", c.McRouterAuthToken)
+				}
+			},
+		},
+		{
+			name:        "mc router apikey auth without token",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-mc-router-auth-type=apikey"},
+			envAPIKey:   "secret123",
+			expectError: true,
+			errorMsg:    "MC_ROUTER_API_KEY is required when mc-router-auth-type is apikey",
+		},
+		{
+			name:        "invalid mc router auth type",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-mc-router-auth-type=basic"},
+			expectError: true,
+			errorMsg:    "invalid mc-router-auth-type: basic (must be apikey or none)",
+		},
 		{
 			name:        "invalid auth type",
 			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-auth-type=invalid"},
@@ -89,8 +339,165 @@ func TestLoadConfigFromFlags(t *testing.T) {
 				if c.SyncInterval != 60*time.Second {
 					t.Errorf("expected SyncInterval to be 60s, got %s", c.SyncInterval)
 				}
+				if c.ReadinessStale != 180*time.Second {
+					t.Errorf("expected ReadinessStale to default to 3 sync intervals, got %s", c.ReadinessStale)
+				}
+			},
+		},
+		{
+			name: "dry run",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-dry-run"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if !c.DryRun {
+					t.Error("expected DryRun to be true")
+				}
+			},
+		},
+		{
+			name: "ownership options",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-state-file=/data/state.json", "-prune-unmanaged"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.StateFile != "/data/state.json" {
+					t.Errorf("expected StateFile to be /data/state.json, got %s", c.StateFile)
+				}
+				if !c.PruneUnmanaged {
+					t.Error("expected PruneUnmanaged to be true")
+				}
 			},
 		},
+		{
+			name: "deletion guard",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-max-deletes=10", "-max-delete-percent=25", "-force-deletes"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				expected := DeletionGuard{MaxDeletes: 10, MaxDeletePercent: 25, Override: true}
+				if c.DeletionGuard != expected {
+					t.Errorf("expected DeletionGuard to be %+v, got %+v", expected, c.DeletionGuard)
+				}
+			},
+		},
+		{
+			name: "default retry policy",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.Retry.MaxAttempts != 3 {
+					t.Errorf("expected 3 retry attempts, got %d", c.Retry.MaxAttempts)
+				}
+				if c.Retry.InitialBackoff != 250*time.Millisecond {
+					t.Errorf("expected initial backoff to be 250ms, got %s", c.Retry.InitialBackoff)
+				}
+				if c.RetryBudget != 20 {
+					t.Errorf("expected retry budget to be 20, got %d", c.RetryBudget)
+				}
+			},
+		},
+		{
+			name: "readiness staleness",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-readiness-staleness=45"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.ReadinessStale != 45*time.Second {
+					t.Errorf("expected ReadinessStale to be 45s, got %s", c.ReadinessStale)
+				}
+			},
+		},
+		{
+			name:        "invalid retry attempts",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-retry-attempts=0"},
+			expectError: true,
+			errorMsg:    "retry-attempts must be at least 1",
+		},
+		{
+			name:        "invalid max delete percent",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-max-delete-percent=150"},
+			expectError: true,
+			errorMsg:    "max-delete-percent must be between 0 and 100",
+		},
+		{
+			name: "health check",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-health-check", "-health-check-successes=3", "-remove-unhealthy"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if !c.HealthCheck || !c.RemoveUnhealthy {
+					t.Errorf("expected HealthCheck and RemoveUnhealthy to be enabled")
+				}
+				if c.HealthSuccesses != 3 {
+					t.Errorf("expected HealthSuccesses to be 3, got %d", c.HealthSuccesses)
+				}
+				if c.HealthTimeout != 3*time.Second {
+					t.Errorf("expected HealthTimeout to be 3s, got %s", c.HealthTimeout)
+				}
+			},
+		},
+		{
+			name:        "remove unhealthy without health check",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-remove-unhealthy"},
+			expectError: true,
+			errorMsg:    "remove-unhealthy requires health-check",
+		},
+		{
+			name: "reconcile trigger",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-trigger-debounce=1000"},
+			env:  map[string]string{"RECONCILE_TRIGGER_TOKEN": "trigger-secret"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.TriggerToken != "trigger-secret" {
+					t.Errorf("expected TriggerToken to be trigger-secret, got %s", c.TriggerToken)
+				}
+				if c.TriggerDebounce != time.Second {
+					t.Errorf("expected TriggerDebounce to be 1s, got %s", c.TriggerDebounce)
+				}
+			},
+		},
+		{
+			name:        "webhook without an upstream",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-webhook"},
+			env:         map[string]string{"WEBHOOK_SECRET": "webhook-secret"},
+			expectError: true,
+			errorMsg:    "server-list-api, server-list-file, kubernetes or docker is required",
+		},
+		{
+			name: "webhook",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-webhook", "-webhook-resync-interval=60"},
+			env:  map[string]string{"WEBHOOK_SECRET": "webhook-secret"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if !c.Webhook || c.WebhookSecret != "webhook-secret" {
+					t.Errorf("expected webhook with secret, got %v %s", c.Webhook, c.WebhookSecret)
+				}
+				if c.WebhookResync != time.Minute {
+					t.Errorf("expected WebhookResync to be 1m, got %s", c.WebhookResync)
+				}
+			},
+		},
+		{
+			name:        "webhook without secret",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-webhook"},
+			expectError: true,
+			errorMsg:    "WEBHOOK_SECRET is required when webhook is enabled",
+		},
+		{
+			name: "fallback backend",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-fallback-backend=maintenance:25565", "-fallback-grace-period=60"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.FallbackBackend != "maintenance:25565" {
+					t.Errorf("expected FallbackBackend to be maintenance:25565, got %s", c.FallbackBackend)
+				}
+				if c.FallbackGrace != time.Minute {
+					t.Errorf("expected FallbackGrace to be 1m, got %s", c.FallbackGrace)
+				}
+			},
+		},
+		{
+			name: "fallback backend is normalized",
+			args: []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-fallback-backend=Maintenance.Example.com.:025565"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.FallbackBackend != "maintenance.example.com:25565" {
+					t.Errorf("expected FallbackBackend to be maintenance.example.com:25565, got %s", c.FallbackBackend)
+				}
+			},
+		},
+		{
+			name:        "fallback backend without a port",
+			args:        []string{"cmd", "-mc-router-host=http://localhost:8080", "-server-list-api=http://api.example.com", "-fallback-backend=maintenance"},
+			expectError: true,
+			errorMsg:    `invalid fallback-backend: invalid backend: "maintenance" is not host:port`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -98,6 +505,13 @@ func TestLoadConfigFromFlags(t *testing.T) {
 
 			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
 
+			for _, key := range []string{"SERVER_LIST_API_KEY", "MC_ROUTER_API_KEY", "DOCKER_HOST", "RECONCILE_TRIGGER_TOKEN", "WEBHOOK_SECRET"} {
+				t.Setenv(key, tt.env[key])
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
 			if tt.envAPIKey != "" {
 				os.Setenv("API_KEY", tt.envAPIKey)
 			} else {
@@ -125,3 +539,222 @@ func TestLoadConfigFromFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		args        []string
+		env         map[string]string
+		expectError bool
+		errorMsg    string
+		validate    func(*testing.T, *ParsedConfig)
+	}{
+		{
+			name: "file only",
+			file: `
+mc-router-host: http://localhost:8080
+server-list-api: http://api.example.com
+kubernetes: true
+source-priority: [kubernetes, api]
+sync-interval: 10
+max-deletes: 5
+`,
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.McRouterHost != "http://localhost:8080" || c.ServerListAPI != "http://api.example.com" || !c.Kubernetes {
+					t.Errorf("expected the sources from the file, got %+v", c)
+				}
+				if len(c.SourcePriority) != 2 || c.SourcePriority[0] != "kubernetes" {
+					t.Errorf("expected SourcePriority to be [kubernetes api], got %v", c.SourcePriority)
+				}
+				if c.SyncInterval != 10*time.Second {
+					t.Errorf("expected SyncInterval to be 10s, got %s", c.SyncInterval)
+				}
+				if c.DeletionGuard.MaxDeletes != 5 {
+					t.Errorf("expected MaxDeletes to be 5, got %d", c.DeletionGuard.MaxDeletes)
+				}
+			},
+		},
+		{
+			name: "flags override the file",
+			file: `
+mc-router-host: http://localhost:8080
+server-list-api: http://api.example.com
+sync-interval: 10
+`,
+			args: []string{"-sync-interval=60"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.SyncInterval != time.Minute {
+					t.Errorf("expected the flag to override SyncInterval, got %s", c.SyncInterval)
+				}
+			},
+		},
+		{
+			name: "flag overrides the file key of its alias",
+			file: `
+mc-router-host: http://localhost:8080
+server-list-api: http://api.example.com
+auth-type: none
+`,
+			args: []string{"-server-list-auth-type=apikey"},
+			env:  map[string]string{"SERVER_LIST_API_KEY": "list-secret"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.AuthType != AuthTypeApiKey || c.AuthToken != "list-secret" {
+					t.Errorf("expected the apikey auth from the flag, got %s with token %q", c.AuthType, c.AuthToken)
+				}
+			},
+		},
+		{
+			name: "file key overrides its alias",
+			file: `
+mc-router-host: http://localhost:8080
+server-list-api: http://api.example.com
+auth-type: none
+server-list-auth-type: apikey
+`,
+			env: map[string]string{"SERVER_LIST_API_KEY": "list-secret"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.AuthType != AuthTypeApiKey {
+					t.Errorf("expected AuthType to be apikey, got %s", c.AuthType)
+				}
+			},
+		},
+		{
+			name: "env vars override the file",
+			file: `
+mc-router-host: http://localhost:8080
+server-list-api: http://api.example.com
+sync-interval: 10
+`,
+			env: map[string]string{"MC_ROUTER_SYNC_SYNC_INTERVAL": "20"},
+			validate: func(t *testing.T, c *ParsedConfig) {
+				if c.SyncInterval != 20*time.Second {
+					t.Errorf("expected the env var to override SyncInterval, got %s", c.SyncInterval)
+				}
+			},
+		},
+		{
+			name:        "unknown key",
+			file:        "mc-router-host: http://localhost:8080\nsync-intervall: 10\n",
+			expectError: true,
+			errorMsg:    "unknown key in config file: sync-intervall",
+		},
+		{
+			name:        "zero sync interval",
+			file:        "mc-router-host: http://localhost:8080\nserver-list-api: http://api.example.com\nsync-interval: 0\n",
+			expectError: true,
+			errorMsg:    "sync-interval must be at least 1",
+		},
+		{
+			name:        "invalid value",
+			file:        "mc-router-host: http://localhost:8080\nsync-interval: soon\n",
+			expectError: true,
+			errorMsg:    `invalid sync-interval in config file: parse error`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"API_KEY", "SERVER_LIST_API_KEY", "MC_ROUTER_API_KEY", "DOCKER_HOST", "RECONCILE_TRIGGER_TOKEN", "WEBHOOK_SECRET"} {
+				t.Setenv(key, "")
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			config, err := LoadConfigArgs(append([]string{"-config=" + path}, tt.args...))
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				} else if err.Error() != tt.errorMsg {
+					t.Errorf("expected error %q, got %q", tt.errorMsg, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.validate(t, config)
+		})
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "unset",
+			expected: "",
+		},
+		{
+			name:     "legacy name",
+			env:      map[string]string{"API_KEY": "legacy"},
+			expected: "legacy",
+		},
+		{
+			name:     "earlier name wins",
+			env:      map[string]string{"API_KEY": "legacy", "SERVER_LIST_API_KEY": "current"},
+			expected: "current",
+		},
+		{
+			name:     "prefixed name wins",
+			env:      map[string]string{"SERVER_LIST_API_KEY": "current", "MC_ROUTER_SYNC_SERVER_LIST_API_KEY": "prefixed"},
+			expected: "prefixed",
+		},
+		{
+			name:     "file",
+			env:      map[string]string{"MC_ROUTER_SYNC_SERVER_LIST_API_KEY_FILE": secretFile},
+			expected: "from-file",
+		},
+		{
+			name:        "value and file",
+			env:         map[string]string{"SERVER_LIST_API_KEY": "current", "SERVER_LIST_API_KEY_FILE": secretFile},
+			expectError: true,
+		},
+		{
+			name:        "missing file",
+			env:         map[string]string{"API_KEY_FILE": filepath.Join(t.TempDir(), "missing")},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"SERVER_LIST_API_KEY", "API_KEY"} {
+				for _, name := range []string{EnvPrefix + key, key} {
+					t.Setenv(name, tt.env[name])
+					t.Setenv(name+"_FILE", tt.env[name+"_FILE"])
+				}
+			}
+
+			secret, err := resolveSecret("SERVER_LIST_API_KEY", "API_KEY")
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if secret != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, secret)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+var (
+	ErrDefaultRouteUnknown = errors.New("default route cannot be read from mc-router")
+)
+
+// DefaultRouter is implemented by mc-router clients that can manage the
+// default route used for connections that match no server address.
+type DefaultRouter interface {
+	GetDefaultRouteContext(ctx context.Context) (string, error)
+	SetDefaultRouteContext(ctx context.Context, backend string) error
+}
+
+// splitDefaultRoute separates the default route declaration, if any, from the
+// regular routes of a server list.
+func splitDefaultRoute(routes Routes) (Routes, *Route) {
+	var out Routes
+	var defaultRoute *Route
+
+	for _, route := range routes {
+		if !route.Default {
+			out = append(out, route)
+			continue
+		}
+
+		if defaultRoute != nil {
+			slog.Warn("server list declares more than one default route, using the first", "backend", defaultRoute.Backend, "ignored", route.Backend)
+			continue
+		}
+		defaultRoute = &route
+	}
+
+	return out, defaultRoute
+}
+
+// diffDefaultRoute compares the declared default route with mc-router's. It
+// returns nil when the server list declares no default route, in which case
+// mc-router's default route is left alone.
+func (r *Reconciler) diffDefaultRoute(ctx context.Context, desired *Route) (*ReconcilerDiff, error) {
+	if desired == nil {
+		return nil, nil
+	}
+
+	diff := &ReconcilerDiff{
+		Default:        true,
+		DesiredBackend: desired.Backend,
+		InServerList:   true,
+	}
+
+	dr, ok := r.McRouterClient.(DefaultRouter)
+	if !ok {
+		slog.Warn("server list declares a default route but the mc-router client cannot manage it")
+		return nil, nil
+	}
+
+	current, err := dr.GetDefaultRouteContext(ctx)
+	if errors.Is(err, ErrDefaultRouteUnknown) {
+		// Without a way to read it, the default route is set on every reconcile.
+		return diff, nil
+	}
+	if err != nil {
+		return nil, &StageError{Stage: StageFetchRoutes, Err: fmt.Errorf("failed to get default route: %w", err)}
+	}
+
+	diff.CurrentBackend = current
+	diff.InMcRouter = current != ""
+
+	return diff, nil
+}
+
+func (r *Reconciler) setDefaultRoute(ctx context.Context, backend string) error {
+	dr, ok := r.McRouterClient.(DefaultRouter)
+	if !ok {
+		return fmt.Errorf("mc-router client cannot manage the default route")
+	}
+
+	if err := dr.SetDefaultRouteContext(ctx, backend); err != nil {
+		return fmt.Errorf("failed to set default route: %w", err)
+	}
+
+	return nil
+}
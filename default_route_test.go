@@ -0,0 +1,108 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type mockDefaultMcRouter struct {
+	ContextMcRouter
+	defaultBackend string
+	getErr         error
+	setCalls       []string
+}
+
+func (m *mockDefaultMcRouter) GetDefaultRouteContext(ctx context.Context) (string, error) {
+	return m.defaultBackend, m.getErr
+}
+
+func (m *mockDefaultMcRouter) SetDefaultRouteContext(ctx context.Context, backend string) error {
+	m.setCalls = append(m.setCalls, backend)
+	m.defaultBackend = backend
+	return nil
+}
+
+func TestReconcilerDefaultRoute(t *testing.T) {
+	tests := []struct {
+		name            string
+		serverList      Routes
+		currentDefault  string
+		getErr          error
+		expectedSetCall []string
+	}{
+		{
+			name: "sets missing default route",
+			serverList: Routes{
+				{Default: true, Backend: "lobby:25565"},
+			},
+			expectedSetCall: []string{"lobby:25565"},
+		},
+		{
+			name: "updates changed default route",
+			serverList: Routes{
+				{Default: true, Backend: "lobby:25565"},
+			},
+			currentDefault:  "old-lobby:25565",
+			expectedSetCall: []string{"lobby:25565"},
+		},
+		{
+			name: "default route in sync",
+			serverList: Routes{
+				{Default: true, Backend: "lobby:25565"},
+			},
+			currentDefault: "lobby:25565",
+		},
+		{
+			name:           "leaves default route alone when not declared",
+			serverList:     Routes{},
+			currentDefault: "lobby:25565",
+		},
+		{
+			name: "sets default route when it can't be read",
+			serverList: Routes{
+				{Default: true, Backend: "lobby:25565"},
+			},
+			currentDefault:  "lobby:25565",
+			getErr:          ErrDefaultRouteUnknown,
+			expectedSetCall: []string{"lobby:25565"},
+		},
+		{
+			name: "first default route wins",
+			serverList: Routes{
+				{Default: true, Backend: "lobby:25565"},
+				{Default: true, Backend: "other:25565"},
+			},
+			expectedSetCall: []string{"lobby:25565"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sl := &mockServerList{routes: tt.serverList}
+			mr := &mockMcRouter{routes: Routes{}}
+			dr := &mockDefaultMcRouter{
+				ContextMcRouter: AdaptMcRouter(mr),
+				defaultBackend:  tt.currentDefault,
+				getErr:          tt.getErr,
+			}
+
+			reconciler := NewReconcilerContext(AdaptServerList(sl), dr, 30*time.Second)
+			if err := reconciler.Reconcile(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(dr.setCalls) != len(tt.expectedSetCall) {
+				t.Fatalf("expected set calls %v, got %v", tt.expectedSetCall, dr.setCalls)
+			}
+			for i := range tt.expectedSetCall {
+				if dr.setCalls[i] != tt.expectedSetCall[i] {
+					t.Errorf("expected set calls %v, got %v", tt.expectedSetCall, dr.setCalls)
+				}
+			}
+			if mr.registerCallCount != 0 || mr.deleteCallCount != 0 {
+				t.Error("expected the default route to not be registered as a regular route")
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// LabelServerAddress holds the comma separated server addresses routed to
+	// a labeled container.
+	LabelServerAddress = "mc-router.seedloaf.server-address"
+	// LabelPort is the container port to route to, defaults to 25565.
+	LabelPort = "mc-router.seedloaf.port"
+	// LabelNetwork picks the network whose IP is used when the container is
+	// attached to several.
+	LabelNetwork = "mc-router.seedloaf.network"
+
+	DefaultDockerHost = "unix:///var/run/docker.sock"
+)
+
+type DockerServerListOpts struct {
+	// Host is the Docker Engine address, either unix:///path/to/docker.sock
+	// or tcp://host:port. Defaults to DefaultDockerHost.
+	Host string
+	// Network is used for containers without a LabelNetwork label. When both
+	// are empty the first network with an IP, by name, is used.
+	Network string
+}
+
+// DockerServerList discovers routes from running containers labeled with
+// LabelServerAddress, using the Docker Engine API. Watch follows the Docker
+// events stream and signals Changes when containers start or stop.
+type DockerServerList struct {
+	baseURL string
+	client  *http.Client
+	network string
+	changes chan struct{}
+}
+
+type dockerContainer struct {
+	ID              string            `json:"Id"`
+	Names           []string          `json:"Names"`
+	Labels          map[string]string `json:"Labels"`
+	NetworkSettings struct {
+		Networks map[string]dockerNetwork `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+type dockerNetwork struct {
+	IPAddress string `json:"IPAddress"`
+}
+
+type dockerEvent struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID string `json:"ID"`
+	} `json:"Actor"`
+}
+
+func NewDockerServerList(opts DockerServerListOpts) (*DockerServerList, error) {
+	host := opts.Host
+	if host == "" {
+		host = DefaultDockerHost
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %s: %w", host, err)
+	}
+
+	d := &DockerServerList{
+		network: opts.Network,
+		changes: make(chan struct{}, 1),
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		d.client = &http.Client{Transport: transport}
+		// The host is ignored when dialing the socket.
+		d.baseURL = "http://docker"
+	case "tcp", "http":
+		d.client = &http.Client{}
+		d.baseURL = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported docker host %s: must be unix:// or tcp://", host)
+	}
+
+	return d, nil
+}
+
+func (d *DockerServerList) GetServers() (Routes, error) {
+	return d.GetServersContext(context.Background())
+}
+
+func (d *DockerServerList) GetServersContext(ctx context.Context) (Routes, error) {
+	filters, _ := json.Marshal(map[string][]string{
+		"label":  {LabelServerAddress},
+		"status": {"running"},
+	})
+
+	resp, err := d.get(ctx, "/containers/json", url.Values{"filters": {string(filters)}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var containers []dockerContainer
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("failed to decode containers: %w", err)
+	}
+
+	routes := Routes{}
+	for _, container := range containers {
+		containerRoutes, err := d.containerRoutes(container)
+		if err != nil {
+			slog.Warn("Skipping container", "container", containerName(container), "err", err)
+			continue
+		}
+		routes = append(routes, containerRoutes...)
+	}
+
+	sortRoutes(routes)
+	return routes, nil
+}
+
+// Changes is signalled whenever Watch sees a container start or stop.
+func (d *DockerServerList) Changes() <-chan struct{} {
+	return d.changes
+}
+
+// Watch follows the Docker events stream until ctx is done, reconnecting
+// with a backoff when the stream fails.
+func (d *DockerServerList) Watch(ctx context.Context) {
+	backoff := RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+
+	failures := 0
+	for {
+		connected, err := d.watchEvents(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			failures = 0
+		}
+
+		failures++
+		delay := backoff.Backoff(failures)
+		slog.Warn("Docker events stream ended", "err", err, "retryIn", delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// watchEvents reports whether it connected to the events stream along with
+// the error that ended it.
+func (d *DockerServerList) watchEvents(ctx context.Context) (bool, error) {
+	filters, _ := json.Marshal(map[string][]string{
+		"type":  {"container", "network"},
+		"event": {"start", "die", "destroy", "connect", "disconnect"},
+	})
+
+	resp, err := d.get(ctx, "/events", url.Values{"filters": {string(filters)}})
+	if err != nil {
+		return false, fmt.Errorf("failed to subscribe to events: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Containers may have changed while the stream was down.
+	d.notify()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event dockerEvent
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return true, fmt.Errorf("events stream closed")
+			}
+			return true, fmt.Errorf("failed to decode event: %w", err)
+		}
+
+		slog.Debug("Docker event", "type", event.Type, "action", event.Action, "id", event.Actor.ID)
+		d.notify()
+	}
+}
+
+func (d *DockerServerList) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
+
+func (d *DockerServerList) containerRoutes(container dockerContainer) (Routes, error) {
+	var addresses []string
+	for _, addr := range strings.Split(container.Labels[LabelServerAddress], ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addresses = append(addresses, addr)
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	port := defaultMinecraftPort
+	if label := container.Labels[LabelPort]; label != "" {
+		p, err := strconv.Atoi(label)
+		if err != nil || p <= 0 || p > 65535 {
+			return nil, fmt.Errorf("invalid %s label: %s", LabelPort, label)
+		}
+		port = p
+	}
+
+	ip, err := d.containerIP(container)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildRoutes(addresses, ip, port), nil
+}
+
+func (d *DockerServerList) containerIP(container dockerContainer) (string, error) {
+	networks := container.NetworkSettings.Networks
+
+	network := container.Labels[LabelNetwork]
+	if network == "" {
+		network = d.network
+	}
+	if network != "" {
+		settings, ok := networks[network]
+		if !ok || settings.IPAddress == "" {
+			return "", fmt.Errorf("container has no IP on network %s", network)
+		}
+		return settings.IPAddress, nil
+	}
+
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if ip := networks[name].IPAddress; ip != "" {
+			return ip, nil
+		}
+	}
+
+	return "", fmt.Errorf("container has no network IP")
+}
+
+func (d *DockerServerList) notify() {
+	select {
+	case d.changes <- struct{}{}:
+	default:
+	}
+}
+
+func containerName(container dockerContainer) string {
+	if len(container.Names) > 0 {
+		return strings.TrimPrefix(container.Names[0], "/")
+	}
+	return container.ID
+}
@@ -0,0 +1,186 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startFakeDockerAPI serves the container list and events endpoints of the
+// Docker Engine API on a unix socket, returning its docker host.
+func startFakeDockerAPI(t *testing.T, containers *[]dockerContainer, events chan string) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "docker")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		var filters map[string][]string
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters); err != nil {
+			t.Errorf("invalid filters: %v", err)
+		}
+		if len(filters["label"]) != 1 || filters["label"][0] != LabelServerAddress {
+			t.Errorf("expected label filter %s, got %v", LabelServerAddress, filters["label"])
+		}
+		json.NewEncoder(w).Encode(*containers)
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event := <-events:
+				w.Write([]byte(event + "\n"))
+				w.(http.Flusher).Flush()
+			}
+		}
+	})
+
+	server := &http.Server{Handler: mux}
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+
+	return "unix://" + socket
+}
+
+func testContainer(name string, labels map[string]string, networks map[string]string) dockerContainer {
+	c := dockerContainer{
+		ID:     name + "-id",
+		Names:  []string{"/" + name},
+		Labels: labels,
+	}
+	c.NetworkSettings.Networks = make(map[string]dockerNetwork)
+	for network, ip := range networks {
+		c.NetworkSettings.Networks[network] = dockerNetwork{IPAddress: ip}
+	}
+	return c
+}
+
+func TestDockerServerListGetServers(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers []dockerContainer
+		network    string
+		expected   Routes
+	}{
+		{
+			name: "labeled containers",
+			containers: []dockerContainer{
+				testContainer("lobby", map[string]string{LabelServerAddress: "lobby.example.com"}, map[string]string{"minecraft": "172.18.0.2"}),
+				testContainer("survival", map[string]string{LabelServerAddress: "survival.example.com,smp.example.com", LabelPort: "25566"}, map[string]string{"minecraft": "172.18.0.3"}),
+			},
+			expected: Routes{
+				{ServerAddress: "lobby.example.com", Backend: "172.18.0.2:25565"},
+				{ServerAddress: "smp.example.com", Backend: "172.18.0.3:25566"},
+				{ServerAddress: "survival.example.com", Backend: "172.18.0.3:25566"},
+			},
+		},
+		{
+			name: "network label picks the network",
+			containers: []dockerContainer{
+				testContainer("lobby", map[string]string{LabelServerAddress: "lobby.example.com", LabelNetwork: "proxy"}, map[string]string{"backend": "172.18.0.2", "proxy": "172.19.0.2"}),
+			},
+			expected: Routes{
+				{ServerAddress: "lobby.example.com", Backend: "172.19.0.2:25565"},
+			},
+		},
+		{
+			name: "default network",
+			containers: []dockerContainer{
+				testContainer("lobby", map[string]string{LabelServerAddress: "lobby.example.com"}, map[string]string{"backend": "172.18.0.2", "proxy": "172.19.0.2"}),
+			},
+			network: "proxy",
+			expected: Routes{
+				{ServerAddress: "lobby.example.com", Backend: "172.19.0.2:25565"},
+			},
+		},
+		{
+			name: "skips containers without an IP or with an invalid port",
+			containers: []dockerContainer{
+				testContainer("host", map[string]string{LabelServerAddress: "host.example.com"}, map[string]string{"host": ""}),
+				testContainer("bad-port", map[string]string{LabelServerAddress: "bad.example.com", LabelPort: "minecraft"}, map[string]string{"minecraft": "172.18.0.4"}),
+			},
+			expected: Routes{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := startFakeDockerAPI(t, &tt.containers, make(chan string))
+
+			d, err := NewDockerServerList(DockerServerListOpts{Host: host, Network: tt.network})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			routes, err := d.GetServers()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(routes) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, routes)
+			}
+			for i, route := range tt.expected {
+				if routes[i] != route {
+					t.Errorf("expected %v, got %v", route, routes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewDockerServerListInvalidHost(t *testing.T) {
+	if _, err := NewDockerServerList(DockerServerListOpts{Host: "ssh://docker.example.com"}); err == nil {
+		t.Error("expected error for unsupported host")
+	}
+}
+
+func TestDockerServerListWatch(t *testing.T) {
+	containers := []dockerContainer{}
+	events := make(chan string)
+	host := startFakeDockerAPI(t, &containers, events)
+
+	d, err := NewDockerServerList(DockerServerListOpts{Host: host})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Watch(ctx)
+
+	waitForChange := func() {
+		t.Helper()
+		select {
+		case <-d.Changes():
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for change")
+		}
+	}
+
+	// Connecting to the stream signals a change in case events were missed.
+	waitForChange()
+
+	events <- `{"Type":"container","Action":"start","Actor":{"ID":"lobby-id"}}`
+	waitForChange()
+
+	events <- `{"Type":"container","Action":"die","Actor":{"ID":"lobby-id"}}`
+	waitForChange()
+}
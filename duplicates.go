@@ -0,0 +1,132 @@
+package mcrouterdiscovery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrDuplicateRoute = errors.New("duplicate server address")
+)
+
+// DuplicatePolicy decides what happens when the server list has several
+// entries for the same server address with different backends.
+type DuplicatePolicy string
+
+const (
+	// DuplicateKeepLast keeps the last entry and logs a warning. It is the
+	// default, matching how duplicates were handled before they were
+	// detected.
+	DuplicateKeepLast DuplicatePolicy = "keep-last"
+	// DuplicateKeepFirst keeps the first entry and logs a warning.
+	DuplicateKeepFirst DuplicatePolicy = "keep-first"
+	// DuplicateError fails the reconcile until the duplicates are removed.
+	DuplicateError DuplicatePolicy = "error"
+)
+
+func GetDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch DuplicatePolicy(s) {
+	case DuplicateKeepLast, DuplicateKeepFirst, DuplicateError:
+		return DuplicatePolicy(s), nil
+	default:
+		return "", fmt.Errorf("invalid duplicate policy: %s (must be keep-last, keep-first or error)", s)
+	}
+}
+
+// DuplicateRoute reports a server address listed with different backends.
+type DuplicateRoute struct {
+	ServerAddress string `json:"serverAddress"`
+	// Backends are the distinct backends in the order they were listed.
+	Backends []string `json:"backends"`
+	// Kept is the backend that was used, empty with DuplicateError.
+	Kept string `json:"kept,omitempty"`
+}
+
+func (d DuplicateRoute) String() string {
+	return fmt.Sprintf("%s: %s", d.ServerAddress, strings.Join(d.Backends, ", "))
+}
+
+// dedupeRoutes keeps one route per server address according to policy and
+// reports every address listed with more than one backend. Entries repeating
+// the same backend are not reported. Default routes are left to
+// splitDefaultRoute.
+func dedupeRoutes(routes Routes, policy DuplicatePolicy) (Routes, []DuplicateRoute) {
+	var out Routes
+	index := make(map[string]int)
+	conflicts := make(map[string]*DuplicateRoute)
+	var order []string
+
+	for _, route := range routes {
+		if route.Default {
+			out = append(out, route)
+			continue
+		}
+
+		i, seen := index[route.ServerAddress]
+		if !seen {
+			index[route.ServerAddress] = len(out)
+			out = append(out, route)
+			continue
+		}
+
+		conflict, ok := conflicts[route.ServerAddress]
+		if !ok {
+			conflict = &DuplicateRoute{ServerAddress: route.ServerAddress, Backends: []string{out[i].Backend}}
+			conflicts[route.ServerAddress] = conflict
+			order = append(order, route.ServerAddress)
+		}
+		if !slices.Contains(conflict.Backends, route.Backend) {
+			conflict.Backends = append(conflict.Backends, route.Backend)
+		}
+
+		if policy != DuplicateKeepFirst {
+			out[i] = route
+		}
+	}
+
+	var duplicates []DuplicateRoute
+	for _, addr := range order {
+		conflict := conflicts[addr]
+		if len(conflict.Backends) < 2 {
+			continue
+		}
+		if policy != DuplicateError {
+			conflict.Kept = out[index[addr]].Backend
+		}
+		duplicates = append(duplicates, *conflict)
+	}
+
+	return out, duplicates
+}
+
+// checkDuplicates removes duplicate server addresses from routes, failing if
+// DuplicatePolicy is DuplicateError and there are any.
+func (r *Reconciler) checkDuplicates(routes Routes) (Routes, []DuplicateRoute, error) {
+	routes, duplicates := dedupeRoutes(routes, r.DuplicatePolicy)
+
+	if len(duplicates) > 0 && r.DuplicatePolicy == DuplicateError {
+		descriptions := make([]string, len(duplicates))
+		for i, duplicate := range duplicates {
+			descriptions[i] = duplicate.String()
+		}
+		return nil, duplicates, &StageError{Stage: StageValidate, Err: fmt.Errorf("%w: %s", ErrDuplicateRoute, strings.Join(descriptions, "; "))}
+	}
+
+	return routes, duplicates, nil
+}
+
+func (r *Reconciler) recordDuplicates(duplicates []DuplicateRoute) {
+	if r.DuplicatePolicy != DuplicateError {
+		for _, duplicate := range duplicates {
+			slog.Warn("Server address is listed with several backends", "serverAddress", duplicate.ServerAddress, "backends", duplicate.Backends, "kept", duplicate.Kept)
+		}
+	}
+
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+
+	r.status.Duplicates = duplicates
+}
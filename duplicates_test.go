@@ -0,0 +1,132 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDedupeRoutes(t *testing.T) {
+	routes := Routes{
+		{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+		{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+		{ServerAddress: "lobby.example.com", Backend: "lobby-2:25565"},
+		{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+		{Default: true, Backend: "limbo:25565"},
+	}
+
+	tests := []struct {
+		name         string
+		policy       DuplicatePolicy
+		expected     Routes
+		expectedKept string
+	}{
+		{
+			name:   "keep last",
+			policy: DuplicateKeepLast,
+			expected: Routes{
+				{ServerAddress: "lobby.example.com", Backend: "lobby-2:25565"},
+				{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+				{Default: true, Backend: "limbo:25565"},
+			},
+			expectedKept: "lobby-2:25565",
+		},
+		{
+			name:   "unset policy keeps last",
+			policy: "",
+			expected: Routes{
+				{ServerAddress: "lobby.example.com", Backend: "lobby-2:25565"},
+				{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+				{Default: true, Backend: "limbo:25565"},
+			},
+			expectedKept: "lobby-2:25565",
+		},
+		{
+			name:   "keep first",
+			policy: DuplicateKeepFirst,
+			expected: Routes{
+				{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+				{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+				{Default: true, Backend: "limbo:25565"},
+			},
+			expectedKept: "lobby:25565",
+		},
+		{
+			name:   "error",
+			policy: DuplicateError,
+			expected: Routes{
+				{ServerAddress: "lobby.example.com", Backend: "lobby-2:25565"},
+				{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+				{Default: true, Backend: "limbo:25565"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, duplicates := dedupeRoutes(routes, tt.policy)
+
+			if len(out) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, out)
+			}
+			for i, route := range tt.expected {
+				if out[i] != route {
+					t.Errorf("expected %v, got %v", route, out[i])
+				}
+			}
+
+			// Repeating the same backend is not a conflict.
+			if len(duplicates) != 1 {
+				t.Fatalf("expected 1 duplicate, got %v", duplicates)
+			}
+			if duplicates[0].String() != "lobby.example.com: lobby:25565, lobby-2:25565" {
+				t.Errorf("expected both backends to be reported, got %s", duplicates[0])
+			}
+			if duplicates[0].Kept != tt.expectedKept {
+				t.Errorf("expected %q to be kept, got %q", tt.expectedKept, duplicates[0].Kept)
+			}
+		})
+	}
+}
+
+func TestReconcilerDuplicatePolicy(t *testing.T) {
+	serverList := &mockServerList{routes: Routes{
+		{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+		{ServerAddress: "Lobby.example.com", Backend: "lobby-2:25565"},
+	}}
+
+	t.Run("error", func(t *testing.T) {
+		mr := &mockMcRouter{routes: Routes{}}
+		reconciler := NewReconciler(serverList, mr, 0)
+		reconciler.DuplicatePolicy = DuplicateError
+
+		err := reconciler.Reconcile(context.Background())
+		if !errors.Is(err, ErrDuplicateRoute) {
+			t.Fatalf("expected ErrDuplicateRoute, got %v", err)
+		}
+		var stageErr *StageError
+		if !errors.As(err, &stageErr) || stageErr.Stage != StageValidate {
+			t.Errorf("expected a validate stage error, got %v", err)
+		}
+		if mr.registerCallCount != 0 || mr.getRoutesCallCount != 0 {
+			t.Error("expected mc-router to be left alone")
+		}
+		// mc-router was never queried, so it isn't reported reachable.
+		if status := reconciler.Status(); len(status.Duplicates) != 1 || !status.ServerListReachable || status.McRouterReachable {
+			t.Errorf("expected the duplicate in a status with only the server list reachable, got %+v", status)
+		}
+	})
+
+	t.Run("keep first", func(t *testing.T) {
+		mr := &mockMcRouter{routes: Routes{}}
+		reconciler := NewReconciler(serverList, mr, 0)
+		reconciler.DuplicatePolicy = DuplicateKeepFirst
+
+		if err := reconciler.Reconcile(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(mr.registered) != 1 || mr.registered[0].Backend != "lobby:25565" {
+			t.Errorf("expected the first backend to be registered, got %v", mr.registered)
+		}
+	})
+}
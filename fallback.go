@@ -0,0 +1,103 @@
+package mcrouterdiscovery
+
+import (
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// FallbackReason explains why a route points at the fallback backend.
+type FallbackReason string
+
+const (
+	FallbackUnhealthy FallbackReason = "unhealthy"
+	FallbackMissing   FallbackReason = "missing"
+)
+
+// FallbackRoute is a route that points at the fallback backend instead of
+// its own Backend.
+type FallbackRoute struct {
+	ServerAddress string         `json:"serverAddress"`
+	Backend       string         `json:"backend"`
+	Reason        FallbackReason `json:"reason"`
+	Since         time.Time      `json:"since"`
+}
+
+// markFallback records that addr points at the fallback backend this cycle,
+// keeping the time it was first sent there for the same reason.
+func (r *Reconciler) markFallback(fallbacks map[string]FallbackRoute, addr, backend string, reason FallbackReason) FallbackRoute {
+	fallback := FallbackRoute{
+		ServerAddress: addr,
+		Backend:       backend,
+		Reason:        reason,
+		Since:         time.Now(),
+	}
+	if prev, ok := r.fallbacks[addr]; ok && prev.Reason == reason {
+		fallback.Since = prev.Since
+	}
+
+	fallbacks[addr] = fallback
+	return fallback
+}
+
+// fallbackMissing points routes that disappeared from the server list at the
+// fallback backend instead of deleting them, until they have been missing for
+// FallbackGracePeriod.
+func (r *Reconciler) fallbackMissing(actions []Action, fallbacks map[string]FallbackRoute) []Action {
+	if r.FallbackBackend == "" || r.FallbackGracePeriod <= 0 {
+		return actions
+	}
+
+	now := time.Now()
+	var out []Action
+	for _, action := range actions {
+		if action.Type != ActionDelete {
+			out = append(out, action)
+			continue
+		}
+
+		// Once a route is on the fallback, mc-router no longer knows its
+		// original backend.
+		backend := action.CurrentBackend
+		if prev, ok := r.fallbacks[action.ServerAddress]; ok {
+			backend = prev.Backend
+		}
+
+		fallback := r.markFallback(fallbacks, action.ServerAddress, backend, FallbackMissing)
+		if now.Sub(fallback.Since) >= r.FallbackGracePeriod {
+			delete(fallbacks, action.ServerAddress)
+			out = append(out, action)
+			continue
+		}
+
+		if action.CurrentBackend == r.FallbackBackend {
+			continue
+		}
+
+		slog.Info("Pointing missing route at fallback backend", "serverAddress", action.ServerAddress, "backend", action.CurrentBackend, "fallback", r.FallbackBackend)
+		out = append(out, pointAt(action.ServerAddress, r.FallbackBackend, action.CurrentBackend))
+	}
+
+	return out
+}
+
+func (r *Reconciler) recordFallbacks(fallbacks map[string]FallbackRoute) {
+	r.fallbacksMu.Lock()
+	r.fallbacks = fallbacks
+	r.fallbacksMu.Unlock()
+
+	routes := make([]FallbackRoute, 0, len(fallbacks))
+	for _, fallback := range fallbacks {
+		routes = append(routes, fallback)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].ServerAddress < routes[j].ServerAddress
+	})
+
+	r.Metrics.observeFallbacks(len(routes))
+
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+
+	r.status.Fallbacks = routes
+}
@@ -0,0 +1,237 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReconcilerFallback(t *testing.T) {
+	down := map[string]error{"backend1:25565": errors.New("down")}
+
+	tests := []struct {
+		name               string
+		serverList         Routes
+		mcRouter           Routes
+		errs               map[string]error
+		owned              []string
+		missingFor         time.Duration
+		expectedRegistered Routes
+		expectedDeleted    []string
+		expectedFallbacks  []FallbackRoute
+	}{
+		{
+			name: "new route to unhealthy backend points at fallback",
+			serverList: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			errs: down,
+			expectedRegistered: Routes{
+				{ServerAddress: "server1.example.com", Backend: "maintenance:25565"},
+			},
+			expectedFallbacks: []FallbackRoute{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565", Reason: FallbackUnhealthy},
+			},
+		},
+		{
+			name: "registered route to unhealthy backend points at fallback",
+			serverList: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			mcRouter: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			errs: down,
+			expectedRegistered: Routes{
+				{ServerAddress: "server1.example.com", Backend: "maintenance:25565"},
+			},
+			expectedFallbacks: []FallbackRoute{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565", Reason: FallbackUnhealthy},
+			},
+		},
+		{
+			name: "route stays on fallback while backend is unhealthy",
+			serverList: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			mcRouter: Routes{
+				{ServerAddress: "server1.example.com", Backend: "maintenance:25565"},
+			},
+			errs: down,
+			expectedFallbacks: []FallbackRoute{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565", Reason: FallbackUnhealthy},
+			},
+		},
+		{
+			name: "route is restored once backend is healthy",
+			serverList: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			mcRouter: Routes{
+				{ServerAddress: "server1.example.com", Backend: "maintenance:25565"},
+			},
+			expectedRegistered: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+		},
+		{
+			name: "missing route points at fallback",
+			mcRouter: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			owned: []string{"server1.example.com"},
+			expectedRegistered: Routes{
+				{ServerAddress: "server1.example.com", Backend: "maintenance:25565"},
+			},
+			expectedFallbacks: []FallbackRoute{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565", Reason: FallbackMissing},
+			},
+		},
+		{
+			name: "unmanaged missing route is left alone",
+			mcRouter: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+		},
+		{
+			name: "missing route is deleted after grace period",
+			mcRouter: Routes{
+				{ServerAddress: "server1.example.com", Backend: "maintenance:25565"},
+			},
+			owned:           []string{"server1.example.com"},
+			missingFor:      time.Hour,
+			expectedDeleted: []string{"server1.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sl := &mockServerList{routes: tt.serverList}
+			mr := &mockMcRouter{routes: tt.mcRouter}
+
+			store := NewMemoryOwnershipStore()
+			for _, addr := range tt.owned {
+				store.Claim(addr)
+			}
+
+			reconciler := NewReconciler(sl, mr, 30*time.Second)
+			reconciler.Ownership = store
+			reconciler.HealthChecker = NewHealthChecker(&mockPinger{errs: tt.errs}, 1)
+			reconciler.HealthChecker.Concurrency = 1
+			reconciler.FallbackBackend = "maintenance:25565"
+			reconciler.FallbackGracePeriod = 10 * time.Minute
+			if tt.missingFor > 0 {
+				reconciler.fallbacks = map[string]FallbackRoute{
+					"server1.example.com": {ServerAddress: "server1.example.com", Backend: "backend1:25565", Reason: FallbackMissing, Since: time.Now().Add(-tt.missingFor)},
+				}
+			}
+
+			if err := reconciler.Reconcile(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(mr.registered) != len(tt.expectedRegistered) {
+				t.Fatalf("expected registered %v, got %v", tt.expectedRegistered, mr.registered)
+			}
+			for i, route := range tt.expectedRegistered {
+				if mr.registered[i] != route {
+					t.Errorf("expected registered %v, got %v", route, mr.registered[i])
+				}
+			}
+
+			if len(mr.deleted) != len(tt.expectedDeleted) {
+				t.Errorf("expected deleted %v, got %v", tt.expectedDeleted, mr.deleted)
+			}
+
+			fallbacks := reconciler.Status().Fallbacks
+			if len(fallbacks) != len(tt.expectedFallbacks) {
+				t.Fatalf("expected fallbacks %v, got %v", tt.expectedFallbacks, fallbacks)
+			}
+			for i, expected := range tt.expectedFallbacks {
+				got := fallbacks[i]
+				if got.ServerAddress != expected.ServerAddress || got.Backend != expected.Backend || got.Reason != expected.Reason {
+					t.Errorf("expected fallback %+v, got %+v", expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcilerFallbackKeepsSince(t *testing.T) {
+	sl := &mockServerList{routes: Routes{}}
+	mr := &mockMcRouter{
+		routes: Routes{
+			{ServerAddress: "server1.example.com", Backend: "maintenance:25565"},
+		},
+	}
+
+	store := NewMemoryOwnershipStore()
+	store.Claim("server1.example.com")
+
+	reconciler := NewReconciler(sl, mr, 30*time.Second)
+	reconciler.Ownership = store
+	reconciler.FallbackBackend = "maintenance:25565"
+	reconciler.FallbackGracePeriod = 10 * time.Minute
+
+	var since time.Time
+	for i := range 2 {
+		if err := reconciler.Reconcile(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		fallbacks := reconciler.Status().Fallbacks
+		if len(fallbacks) != 1 {
+			t.Fatalf("expected 1 fallback, got %v", fallbacks)
+		}
+		if i > 0 && !fallbacks[0].Since.Equal(since) {
+			t.Errorf("expected since to stay %s, got %s", since, fallbacks[0].Since)
+		}
+		since = fallbacks[0].Since
+	}
+
+	if mr.deleteCallCount != 0 {
+		t.Errorf("expected no deletes during the grace period, got %d", mr.deleteCallCount)
+	}
+}
+
+func TestReconcilerPlanFallback(t *testing.T) {
+	sl := &mockServerList{routes: Routes{
+		{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+		{ServerAddress: "server2.example.com", Backend: "backend2:25565"},
+	}}
+	mr := &mockMcRouter{routes: Routes{
+		{ServerAddress: "server3.example.com", Backend: "backend3:25565"},
+	}}
+
+	store := NewMemoryOwnershipStore()
+	store.Claim("server3.example.com")
+
+	reconciler := NewReconciler(sl, mr, 30*time.Second)
+	reconciler.Ownership = store
+	reconciler.HealthChecker = NewHealthChecker(&mockPinger{errs: map[string]error{"backend2:25565": errors.New("connection refused")}}, 1)
+	reconciler.HealthChecker.Concurrency = 1
+	reconciler.FallbackBackend = "maintenance:25565"
+	reconciler.FallbackGracePeriod = 10 * time.Minute
+
+	plan, err := reconciler.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The plan matches what Reconcile would apply: the unhealthy and the
+	// missing route point at the fallback instead of being added or deleted.
+	expected := "add server1.example.com: backend1:25565\n" +
+		"add server2.example.com: maintenance:25565\n" +
+		"update server3.example.com: backend3:25565 -> maintenance:25565"
+	if plan.String() != expected {
+		t.Errorf("expected plan:\n%s\ngot:\n%s", expected, plan)
+	}
+
+	if mr.registerCallCount != 0 || mr.deleteCallCount != 0 {
+		t.Error("expected Plan to not modify mc router")
+	}
+	if fallbacks := reconciler.Status().Fallbacks; len(fallbacks) != 0 {
+		t.Errorf("expected Plan to not record fallbacks, got %v", fallbacks)
+	}
+}
@@ -0,0 +1,128 @@
+package mcrouterdiscovery
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultFilePollInterval = 2 * time.Second
+
+// FileServerList reads routes from a local JSON or YAML file using the same
+// format as the server list API. The format is chosen by file extension,
+// .yaml and .yml are parsed as YAML and everything else as JSON.
+type FileServerList struct {
+	path         string
+	pollInterval time.Duration
+	changes      chan struct{}
+}
+
+func NewFileServerList(path string, pollInterval time.Duration) *FileServerList {
+	if pollInterval <= 0 {
+		pollInterval = DefaultFilePollInterval
+	}
+
+	return &FileServerList{
+		path:         path,
+		pollInterval: pollInterval,
+		changes:      make(chan struct{}, 1),
+	}
+}
+
+func (f *FileServerList) GetServers() (Routes, error) {
+	return f.GetServersContext(context.Background())
+}
+
+func (f *FileServerList) GetServersContext(ctx context.Context) (Routes, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server list file: %w", err)
+	}
+
+	routes, err := parseRoutesFile(f.path, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server list file %s: %w", f.path, err)
+	}
+
+	return routes, nil
+}
+
+// Changes is signalled whenever Watch sees the file contents change.
+func (f *FileServerList) Changes() <-chan struct{} {
+	return f.changes
+}
+
+// Watch polls the file until ctx is done and signals Changes when its
+// contents change. Polling, rather than inotify, also picks up files that are
+// replaced through symlink swaps such as Kubernetes ConfigMap mounts.
+func (f *FileServerList) Watch(ctx context.Context) {
+	pollFile(ctx, f.path, f.pollInterval, func() {
+		slog.Info("Server list file changed", "path", f.path)
+		select {
+		case f.changes <- struct{}{}:
+		default:
+		}
+	})
+}
+
+// pollFile calls onChange every time the contents of the file at path change
+// until ctx is done.
+func pollFile(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := fileChecksum(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := fileChecksum(path)
+			if bytes.Equal(current, last) {
+				continue
+			}
+			last = current
+			onChange()
+		}
+	}
+}
+
+func fileChecksum(path string) []byte {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
+
+func parseRoutesFile(path string, data []byte) (Routes, error) {
+	var routes Routes
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &routes); err != nil {
+			return nil, err
+		}
+	default:
+		if err := json.Unmarshal(data, &routes); err != nil {
+			return nil, err
+		}
+	}
+
+	return routes, nil
+}
@@ -0,0 +1,139 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileServerListGetServers(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileName    string
+		contents    string
+		expectError bool
+		expected    Routes
+	}{
+		{
+			name:     "json file",
+			fileName: "routes.json",
+			contents: `[{"serverAddress": "lobby.example.com", "backend": "lobby:25565"}]`,
+			expected: Routes{{ServerAddress: "lobby.example.com", Backend: "lobby:25565"}},
+		},
+		{
+			name:     "yaml file",
+			fileName: "routes.yaml",
+			contents: "- serverAddress: lobby.example.com\n  backend: lobby:25565\n- serverAddress: survival.example.com\n  backend: survival:25565\n",
+			expected: Routes{
+				{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+				{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+			},
+		},
+		{
+			name:     "yml file",
+			fileName: "routes.yml",
+			contents: "[]",
+			expected: Routes{},
+		},
+		{
+			name:        "invalid json",
+			fileName:    "routes.json",
+			contents:    "- serverAddress: lobby.example.com",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.fileName)
+			if err := os.WriteFile(path, []byte(tt.contents), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			routes, err := NewFileServerList(path, 0).GetServers()
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(routes) != len(tt.expected) {
+				t.Fatalf("expected %d routes, got %d", len(tt.expected), len(routes))
+			}
+			for i, route := range routes {
+				if route != tt.expected[i] {
+					t.Errorf("expected route %+v, got %+v", tt.expected[i], route)
+				}
+			}
+		})
+	}
+}
+
+func TestFileServerListMissingFile(t *testing.T) {
+	_, err := NewFileServerList(filepath.Join(t.TempDir(), "missing.json"), 0).GetServers()
+	if err == nil {
+		t.Error("expected error but got none")
+	}
+}
+
+func TestFileServerListWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(path, []byte("[]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fileList := NewFileServerList(path, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go fileList.Watch(ctx)
+
+	select {
+	case <-fileList.Changes():
+		t.Fatal("expected no change before the file is modified")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"serverAddress": "lobby.example.com", "backend": "lobby:25565"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-fileList.Changes():
+	case <-time.After(time.Second):
+		t.Fatal("expected a change notification after the file was modified")
+	}
+}
+
+func TestReconcilerStartReconcilesOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(path, []byte("[]"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fileList := NewFileServerList(path, 10*time.Millisecond)
+	mr := &mockMcRouter{routes: Routes{}}
+	reconciler := NewReconcilerContext(fileList, AdaptMcRouter(mr), time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	go fileList.Watch(ctx)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		os.WriteFile(path, []byte(`[{"serverAddress": "lobby.example.com", "backend": "lobby:25565"}]`), 0o644)
+	}()
+
+	reconciler.Start(ctx)
+
+	if mr.registerCallCount != 1 {
+		t.Errorf("expected the change to be reconciled before the next tick, got %d registers", mr.registerCallCount)
+	}
+}
@@ -0,0 +1,52 @@
+package mcrouterdiscovery
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrTooManyDeletes = errors.New("too many deletes")
+)
+
+// DeletionGuard refuses plans that would delete more routes than expected,
+// protecting mc-router from a server list that suddenly comes back empty.
+// Zero values disable the corresponding limit.
+type DeletionGuard struct {
+	MaxDeletes       int
+	MaxDeletePercent float64
+	// Override applies the plan anyway, logging that the guard was bypassed.
+	Override bool
+}
+
+func (g DeletionGuard) Enabled() bool {
+	return g.MaxDeletes > 0 || g.MaxDeletePercent > 0
+}
+
+// Check returns ErrTooManyDeletes when the deletes in actions exceed the
+// configured limits. currentRoutes is the number of routes in mc-router.
+func (g DeletionGuard) Check(actions []Action, currentRoutes int) error {
+	deletes := 0
+	for _, action := range actions {
+		if action.Type == ActionDelete {
+			deletes++
+		}
+	}
+
+	if deletes == 0 {
+		return nil
+	}
+
+	if g.MaxDeletes > 0 && deletes > g.MaxDeletes {
+		return fmt.Errorf("%w: plan deletes %d routes, limit is %d", ErrTooManyDeletes, deletes, g.MaxDeletes)
+	}
+
+	if g.MaxDeletePercent > 0 && currentRoutes > 0 {
+		percent := float64(deletes) / float64(currentRoutes) * 100
+		if percent > g.MaxDeletePercent {
+			return fmt.Errorf("%w: plan deletes %d of %d routes (%.1f%%), limit is %.1f%%", ErrTooManyDeletes, deletes, currentRoutes, percent, g.MaxDeletePercent)
+		}
+	}
+
+	return nil
+}
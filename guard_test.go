@@ -0,0 +1,81 @@
+package mcrouterdiscovery
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDeletionGuardCheck(t *testing.T) {
+	deletes := func(n int) []Action {
+		var actions []Action
+		for i := 0; i < n; i++ {
+			actions = append(actions, Action{Type: ActionDelete})
+		}
+		return append(actions, Action{Type: ActionAdd})
+	}
+
+	tests := []struct {
+		name          string
+		guard         DeletionGuard
+		actions       []Action
+		currentRoutes int
+		expectError   bool
+	}{
+		{
+			name:          "disabled guard",
+			guard:         DeletionGuard{},
+			actions:       deletes(10),
+			currentRoutes: 10,
+			expectError:   false,
+		},
+		{
+			name:          "under absolute limit",
+			guard:         DeletionGuard{MaxDeletes: 5},
+			actions:       deletes(5),
+			currentRoutes: 10,
+			expectError:   false,
+		},
+		{
+			name:          "over absolute limit",
+			guard:         DeletionGuard{MaxDeletes: 5},
+			actions:       deletes(6),
+			currentRoutes: 10,
+			expectError:   true,
+		},
+		{
+			name:          "under percent limit",
+			guard:         DeletionGuard{MaxDeletePercent: 50},
+			actions:       deletes(5),
+			currentRoutes: 10,
+			expectError:   false,
+		},
+		{
+			name:          "over percent limit",
+			guard:         DeletionGuard{MaxDeletePercent: 50},
+			actions:       deletes(6),
+			currentRoutes: 10,
+			expectError:   true,
+		},
+		{
+			name:          "no deletes",
+			guard:         DeletionGuard{MaxDeletes: 1, MaxDeletePercent: 1},
+			actions:       deletes(0),
+			currentRoutes: 10,
+			expectError:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.guard.Check(tt.actions, tt.currentRoutes)
+
+			if tt.expectError {
+				if !errors.Is(err, ErrTooManyDeletes) {
+					t.Errorf("expected ErrTooManyDeletes, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -2,20 +2,43 @@ package mcrouterdiscovery
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
 	"log"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func StartHealthServer(ctx context.Context) {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-	})
+type HealthServerOpts struct {
+	// Reconciler, when set, is used to report readiness on /ready.
+	Reconciler *Reconciler
+	// StalenessWindow is how old the last successful reconcile may be before
+	// the service reports itself as not ready. Zero disables the check.
+	StalenessWindow time.Duration
+	// Gatherer, when set, is served on /metrics.
+	Gatherer prometheus.Gatherer
+	// TriggerToken enables POST /reconcile, which triggers an immediate
+	// reconcile for requests carrying it as a bearer token.
+	TriggerToken string
+	// Webhook, when set, receives pushed route changes on /webhook.
+	Webhook http.Handler
+}
+
+type readinessResponse struct {
+	Ready   bool     `json:"ready"`
+	Reasons []string `json:"reasons,omitempty"`
+	Status
+}
 
+func StartHealthServer(ctx context.Context, opts HealthServerOpts) {
 	server := &http.Server{
 		Addr:    ":8080",
-		Handler: mux,
+		Handler: NewHealthHandler(opts),
 	}
 
 	go func() {
@@ -31,3 +54,52 @@ func StartHealthServer(ctx context.Context) {
 		log.Fatalf("Health server failed: %s", err)
 	}
 }
+
+func NewHealthHandler(opts HealthServerOpts) http.Handler {
+	mux := http.NewServeMux()
+
+	// Liveness only reports that the process is serving requests, sync
+	// problems are reported by /ready so that they don't cause restarts.
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		resp := readinessResponse{Ready: true}
+		if opts.Reconciler != nil {
+			resp.Status = opts.Reconciler.Status()
+			resp.Ready, resp.Reasons = resp.Status.Ready(opts.StalenessWindow, time.Now())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !resp.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			slog.Error("failed to write readiness response", "err", err)
+		}
+	})
+
+	if opts.Reconciler != nil && opts.TriggerToken != "" {
+		mux.HandleFunc("POST /reconcile", func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(opts.TriggerToken)) != 1 {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			opts.Reconciler.Trigger()
+			w.WriteHeader(http.StatusAccepted)
+		})
+	}
+
+	if opts.Webhook != nil {
+		mux.Handle("/webhook", opts.Webhook)
+	}
+
+	if opts.Gatherer != nil {
+		mux.Handle("/metrics", promhttp.HandlerFor(opts.Gatherer, promhttp.HandlerOpts{}))
+	}
+
+	return mux
+}
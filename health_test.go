@@ -0,0 +1,220 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthHandlerLiveness(t *testing.T) {
+	sl := &mockServerList{
+		err: fmt.Errorf("connection refused"),
+	}
+	reconciler := NewReconciler(sl, &mockMcRouter{}, 30*time.Second)
+	reconciler.Reconcile(context.Background())
+
+	rec := httptest.NewRecorder()
+	NewHealthHandler(HealthServerOpts{Reconciler: reconciler}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected liveness to stay 200 when syncs fail, got %d", rec.Code)
+	}
+}
+
+func TestHealthHandlerReadiness(t *testing.T) {
+	tests := []struct {
+		name            string
+		serverListErr   error
+		mcRouterErr     error
+		mcRouterRoutes  Routes
+		guard           DeletionGuard
+		stalenessWindow time.Duration
+		skipReconcile   bool
+		expectReady     bool
+		validate        func(t *testing.T, resp readinessResponse)
+	}{
+		{
+			name:        "ready after successful sync",
+			expectReady: true,
+			validate: func(t *testing.T, resp readinessResponse) {
+				if resp.LastSuccess.IsZero() {
+					t.Error("expected lastSuccess to be set")
+				}
+				if resp.DesiredRoutes != 1 {
+					t.Errorf("expected 1 desired route, got %d", resp.DesiredRoutes)
+				}
+				if resp.ActualRoutes != 2 {
+					t.Errorf("expected 2 actual routes, got %d", resp.ActualRoutes)
+				}
+			},
+		},
+		{
+			name:          "not ready before first sync",
+			skipReconcile: true,
+			expectReady:   false,
+		},
+		{
+			name:          "not ready when server list unreachable",
+			serverListErr: fmt.Errorf("connection refused"),
+			expectReady:   false,
+			validate: func(t *testing.T, resp readinessResponse) {
+				if resp.ServerListReachable {
+					t.Error("expected server list to be reported unreachable")
+				}
+				if resp.LastError == "" {
+					t.Error("expected lastError to be set")
+				}
+			},
+		},
+		{
+			name:        "not ready when mc router unreachable",
+			mcRouterErr: fmt.Errorf("connection refused"),
+			expectReady: false,
+			validate: func(t *testing.T, resp readinessResponse) {
+				if !resp.ServerListReachable {
+					t.Error("expected server list to be reported reachable")
+				}
+				if resp.McRouterReachable {
+					t.Error("expected mc router to be reported unreachable")
+				}
+			},
+		},
+		{
+			name:        "not ready when deletion guard tripped",
+			guard:       DeletionGuard{MaxDeletes: 0, MaxDeletePercent: 10},
+			expectReady: false,
+			validate: func(t *testing.T, resp readinessResponse) {
+				if !resp.DeletionGuardTripped {
+					t.Error("expected deletion guard to be reported as tripped")
+				}
+			},
+		},
+		{
+			name:            "not ready when last success is stale",
+			stalenessWindow: time.Nanosecond,
+			expectReady:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sl := &mockServerList{
+				routes: Routes{{ServerAddress: "server1.example.com", Backend: "backend1:25565"}},
+				err:    tt.serverListErr,
+			}
+			mr := &mockMcRouter{
+				routes: Routes{
+					{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+					{ServerAddress: "server2.example.com", Backend: "backend2:25565"},
+				},
+				err: tt.mcRouterErr,
+			}
+
+			reconciler := NewReconciler(sl, mr, 30*time.Second)
+			reconciler.DeletionGuard = tt.guard
+			if !tt.skipReconcile {
+				reconciler.Reconcile(context.Background())
+			}
+			time.Sleep(time.Millisecond)
+
+			rec := httptest.NewRecorder()
+			handler := NewHealthHandler(HealthServerOpts{Reconciler: reconciler, StalenessWindow: tt.stalenessWindow})
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			expectedCode := http.StatusOK
+			if !tt.expectReady {
+				expectedCode = http.StatusServiceUnavailable
+			}
+			if rec.Code != expectedCode {
+				t.Errorf("expected status %d, got %d: %s", expectedCode, rec.Code, rec.Body.String())
+			}
+
+			var resp readinessResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Ready != tt.expectReady {
+				t.Errorf("expected ready to be %v, got %v", tt.expectReady, resp.Ready)
+			}
+			if !resp.Ready && len(resp.Reasons) == 0 {
+				t.Error("expected reasons when not ready")
+			}
+			if tt.validate != nil {
+				tt.validate(t, resp)
+			}
+		})
+	}
+}
+
+func TestHealthHandlerReconcileTrigger(t *testing.T) {
+	tests := []struct {
+		name          string
+		triggerToken  string
+		method        string
+		authorization string
+		expectedCode  int
+		expectTrigger bool
+	}{
+		{
+			name:          "triggers with valid token",
+			triggerToken:  "secret",
+			method:        http.MethodPost,
+			authorization: "Bearer secret",
+			expectedCode:  http.StatusAccepted,
+			expectTrigger: true,
+		},
+		{
+			name:          "rejects invalid token",
+			triggerToken:  "secret",
+			method:        http.MethodPost,
+			authorization: "Bearer wrong",
+			expectedCode:  http.StatusUnauthorized,
+		},
+		{
+			name:         "rejects missing token",
+			triggerToken: "secret",
+			method:       http.MethodPost,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "rejects GET",
+			triggerToken:  "secret",
+			method:        http.MethodGet,
+			authorization: "Bearer secret",
+			expectedCode:  http.StatusMethodNotAllowed,
+		},
+		{
+			name:          "disabled without a token",
+			method:        http.MethodPost,
+			authorization: "Bearer ",
+			expectedCode:  http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reconciler := NewReconciler(&mockServerList{}, &mockMcRouter{}, 30*time.Second)
+			handler := NewHealthHandler(HealthServerOpts{Reconciler: reconciler, TriggerToken: tt.triggerToken})
+
+			req := httptest.NewRequest(tt.method, "/reconcile", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedCode {
+				t.Errorf("expected status %d, got %d", tt.expectedCode, rec.Code)
+			}
+
+			triggered := len(reconciler.triggerChan()) > 0
+			if triggered != tt.expectTrigger {
+				t.Errorf("expected triggered %v, got %v", tt.expectTrigger, triggered)
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package mcrouterdiscovery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMinecraftPort = 25565
+	// slpProtocolVersion is sent in the handshake, by convention -1 is used
+	// when pinging to find out which version a server runs.
+	slpProtocolVersion = -1
+	// maxStatusLength bounds the status response, real responses with a
+	// favicon are well under this.
+	maxStatusLength = 1 << 20
+)
+
+var (
+	ErrInvalidStatusResponse = errors.New("invalid status response")
+)
+
+// Pinger checks whether the Minecraft server at backend is accepting
+// connections.
+type Pinger interface {
+	Ping(ctx context.Context, backend string) error
+}
+
+// SLPPinger pings backends with the Minecraft Java Edition Server List Ping:
+// a handshake followed by a status request, which must be answered with a
+// JSON status response.
+type SLPPinger struct {
+	Timeout time.Duration
+}
+
+func (p SLPPinger) Ping(ctx context.Context, backend string) error {
+	host, port, err := splitBackend(backend)
+	if err != nil {
+		return err
+	}
+
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(int(port))))
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var handshake bytes.Buffer
+	writeVarInt(&handshake, 0x00)
+	writeVarInt(&handshake, slpProtocolVersion)
+	writeString(&handshake, host)
+	binary.Write(&handshake, binary.BigEndian, port)
+	writeVarInt(&handshake, 1) // next state: status
+
+	if err := writePacket(conn, handshake.Bytes()); err != nil {
+		return fmt.Errorf("failed to send handshake: %w", err)
+	}
+	if err := writePacket(conn, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to send status request: %w", err)
+	}
+
+	r := bufio.NewReader(conn)
+	length, err := readVarInt(r)
+	if err != nil {
+		return fmt.Errorf("failed to read status response: %w", err)
+	}
+	if length <= 0 || length > maxStatusLength {
+		return fmt.Errorf("%w: packet length %d", ErrInvalidStatusResponse, length)
+	}
+
+	packet := make([]byte, length)
+	if _, err := io.ReadFull(r, packet); err != nil {
+		return fmt.Errorf("failed to read status response: %w", err)
+	}
+
+	pr := bytes.NewReader(packet)
+	packetID, err := readVarInt(pr)
+	if err != nil || packetID != 0x00 {
+		return fmt.Errorf("%w: unexpected packet id %d", ErrInvalidStatusResponse, packetID)
+	}
+
+	status, err := readString(pr)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidStatusResponse, err)
+	}
+	if !json.Valid([]byte(status)) {
+		return fmt.Errorf("%w: status is not JSON", ErrInvalidStatusResponse)
+	}
+
+	return nil
+}
+
+// HealthChecker tracks consecutive successful pings per backend. A backend
+// is healthy once it has answered RequiredSuccesses pings in a row, and any
+// failed ping resets it.
+type HealthChecker struct {
+	Pinger            Pinger
+	RequiredSuccesses int
+	// Concurrency bounds the number of pings in flight at once.
+	Concurrency int
+
+	mu        sync.Mutex
+	successes map[string]int
+}
+
+func NewHealthChecker(pinger Pinger, requiredSuccesses int) *HealthChecker {
+	return &HealthChecker{
+		Pinger:            pinger,
+		RequiredSuccesses: requiredSuccesses,
+		Concurrency:       32,
+		successes:         make(map[string]int),
+	}
+}
+
+// CheckAll pings every backend once and returns which of them are healthy.
+// Streaks of backends that are no longer checked are forgotten.
+func (h *HealthChecker) CheckAll(ctx context.Context, backends []string) map[string]bool {
+	results := h.pingAll(ctx, backends)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.successes == nil {
+		h.successes = make(map[string]int)
+	}
+
+	for backend := range h.successes {
+		if _, ok := results[backend]; !ok {
+			delete(h.successes, backend)
+		}
+	}
+
+	healthy := make(map[string]bool, len(results))
+	for backend, err := range results {
+		if err != nil {
+			slog.Debug("backend failed health check", "backend", backend, "err", err)
+			h.successes[backend] = 0
+		} else {
+			h.successes[backend]++
+		}
+		healthy[backend] = h.successes[backend] >= h.RequiredSuccesses
+	}
+
+	return healthy
+}
+
+// Probe pings every backend once like CheckAll and returns which of them
+// CheckAll would report healthy, without recording the pings.
+func (h *HealthChecker) Probe(ctx context.Context, backends []string) map[string]bool {
+	results := h.pingAll(ctx, backends)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	healthy := make(map[string]bool, len(results))
+	for backend, err := range results {
+		healthy[backend] = err == nil && h.successes[backend]+1 >= h.RequiredSuccesses
+	}
+
+	return healthy
+}
+
+func (h *HealthChecker) pingAll(ctx context.Context, backends []string) map[string]error {
+	concurrency := h.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make(map[string]error, len(backends))
+	var resultsMu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for _, backend := range backends {
+		resultsMu.Lock()
+		_, seen := results[backend]
+		results[backend] = nil
+		resultsMu.Unlock()
+		if seen {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := h.Pinger.Ping(ctx, backend)
+			resultsMu.Lock()
+			results[backend] = err
+			resultsMu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// filterUnhealthy runs the health check stage between Diff and Apply. Adds
+// to backends that are not yet healthy are held back, or sent to the
+// FallbackBackend when one is set. Routes already in mc-router whose backend
+// stopped answering are moved to the fallback, or deleted when only
+// RemoveUnhealthy is set. Unless record is set, backends are only probed, see
+// HealthChecker.Probe.
+func (r *Reconciler) filterUnhealthy(ctx context.Context, diffs []ReconcilerDiff, actions []Action, fallbacks map[string]FallbackRoute, record bool) []Action {
+	if r.HealthChecker == nil {
+		return actions
+	}
+
+	var backends []string
+	for _, action := range actions {
+		if action.pointsRoute() && action.Backend != r.FallbackBackend {
+			backends = append(backends, action.Backend)
+		}
+	}
+
+	var inSync []ReconcilerDiff
+	for _, diff := range diffs {
+		if diff.Default || !diff.InServerList || !diff.InMcRouter || diff.DesiredBackend != diff.CurrentBackend || diff.CurrentBackend == r.FallbackBackend {
+			continue
+		}
+		if r.FallbackBackend == "" && !(r.RemoveUnhealthy && r.mayDelete(diff)) {
+			continue
+		}
+		inSync = append(inSync, diff)
+		backends = append(backends, diff.CurrentBackend)
+	}
+
+	if len(backends) == 0 {
+		return actions
+	}
+
+	check := r.HealthChecker.CheckAll
+	if !record {
+		check = r.HealthChecker.Probe
+	}
+	healthy := check(ctx, backends)
+
+	var out []Action
+	for _, action := range actions {
+		if !action.pointsRoute() || action.Backend == r.FallbackBackend || healthy[action.Backend] {
+			out = append(out, action)
+			continue
+		}
+
+		if r.FallbackBackend == "" {
+			slog.Info("Holding back route until its backend is healthy", "serverAddress", action.ServerAddress, "backend", action.Backend)
+			continue
+		}
+
+		r.markFallback(fallbacks, action.ServerAddress, action.Backend, FallbackUnhealthy)
+		if action.CurrentBackend == r.FallbackBackend {
+			continue
+		}
+		slog.Info("Pointing route at fallback backend until its backend is healthy", "serverAddress", action.ServerAddress, "backend", action.Backend, "fallback", r.FallbackBackend)
+		out = append(out, pointAt(action.ServerAddress, r.FallbackBackend, action.CurrentBackend))
+	}
+
+	for _, diff := range inSync {
+		if healthy[diff.CurrentBackend] {
+			continue
+		}
+
+		if r.FallbackBackend != "" {
+			r.markFallback(fallbacks, diff.ServerAddress, diff.CurrentBackend, FallbackUnhealthy)
+			slog.Warn("Pointing route to unhealthy backend at fallback backend", "serverAddress", diff.ServerAddress, "backend", diff.CurrentBackend, "fallback", r.FallbackBackend)
+			out = append(out, pointAt(diff.ServerAddress, r.FallbackBackend, diff.CurrentBackend))
+			continue
+		}
+
+		slog.Warn("Removing route to unhealthy backend", "serverAddress", diff.ServerAddress, "backend", diff.CurrentBackend)
+		out = append(out, Action{
+			Type:           ActionDelete,
+			ServerAddress:  diff.ServerAddress,
+			CurrentBackend: diff.CurrentBackend,
+		})
+	}
+
+	return out
+}
+
+func splitBackend(backend string) (string, uint16, error) {
+	host, portStr, err := net.SplitHostPort(backend)
+	if err != nil {
+		// Backends without a port use the Minecraft default.
+		return backend, defaultMinecraftPort, nil
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in backend %s: %w", backend, err)
+	}
+
+	return host, uint16(port), nil
+}
+
+func writePacket(w io.Writer, payload []byte) error {
+	var packet bytes.Buffer
+	writeVarInt(&packet, int32(len(payload)))
+	packet.Write(payload)
+
+	_, err := w.Write(packet.Bytes())
+	return err
+}
+
+func writeVarInt(buf *bytes.Buffer, value int32) {
+	v := uint32(value)
+	for {
+		if v&^0x7F == 0 {
+			buf.WriteByte(byte(v))
+			return
+		}
+		buf.WriteByte(byte(v&0x7F | 0x80))
+		v >>= 7
+	}
+}
+
+func readVarInt(r io.ByteReader) (int32, error) {
+	var value uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(value), nil
+		}
+	}
+	return 0, fmt.Errorf("varint is too long")
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeVarInt(buf, int32(len(s)))
+	buf.WriteString(s)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return "", err
+	}
+	if length < 0 || int(length) > r.Len() {
+		return "", fmt.Errorf("string length %d out of range", length)
+	}
+
+	s := make([]byte, length)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
@@ -0,0 +1,326 @@
+package mcrouterdiscovery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// startFakeMinecraftServer serves the status half of the Server List Ping,
+// answering every status request with status. It returns the listener address.
+func startFakeMinecraftServer(t *testing.T, status string) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveStatus(t, conn, status)
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func serveStatus(t *testing.T, conn net.Conn, status string) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	handshake, err := readTestPacket(r)
+	if err != nil {
+		return
+	}
+	hr := bytes.NewReader(handshake)
+	if id, _ := readVarInt(hr); id != 0x00 {
+		t.Errorf("expected handshake packet id 0, got %d", id)
+		return
+	}
+	readVarInt(hr) // protocol version
+	readString(hr)
+	hr.Seek(2, io.SeekCurrent) // port
+	if nextState, _ := readVarInt(hr); nextState != 1 {
+		t.Errorf("expected next state 1, got %d", nextState)
+		return
+	}
+
+	request, err := readTestPacket(r)
+	if err != nil || !bytes.Equal(request, []byte{0x00}) {
+		t.Errorf("expected status request, got %v (err: %v)", request, err)
+		return
+	}
+
+	var payload bytes.Buffer
+	writeVarInt(&payload, 0x00)
+	writeString(&payload, status)
+	writePacket(conn, payload.Bytes())
+}
+
+func readTestPacket(r *bufio.Reader) ([]byte, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	packet := make([]byte, length)
+	_, err = io.ReadFull(r, packet)
+	return packet, err
+}
+
+func TestSLPPinger(t *testing.T) {
+	healthy := startFakeMinecraftServer(t, `{"version":{"name":"1.21","protocol":767},"players":{"max":20,"online":0}}`)
+	invalid := startFakeMinecraftServer(t, "not json")
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer silent.Close()
+
+	tests := []struct {
+		name        string
+		backend     string
+		expectError bool
+	}{
+		{
+			name:    "healthy server",
+			backend: healthy,
+		},
+		{
+			name:        "invalid status",
+			backend:     invalid,
+			expectError: true,
+		},
+		{
+			name:        "connection refused",
+			backend:     closedAddr,
+			expectError: true,
+		},
+		{
+			name:        "server never answers",
+			backend:     silent.Addr().String(),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := SLPPinger{Timeout: 200 * time.Millisecond}
+			err := pinger.Ping(context.Background(), tt.backend)
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+type mockPinger struct {
+	errs  map[string]error
+	pings map[string]int
+}
+
+func (m *mockPinger) Ping(ctx context.Context, backend string) error {
+	if m.pings == nil {
+		m.pings = make(map[string]int)
+	}
+	m.pings[backend]++
+	return m.errs[backend]
+}
+
+func TestHealthCheckerConsecutiveSuccesses(t *testing.T) {
+	pinger := &mockPinger{errs: map[string]error{}}
+	checker := NewHealthChecker(pinger, 2)
+	checker.Concurrency = 1
+
+	steps := []struct {
+		err      error
+		expected bool
+	}{
+		{expected: false},
+		{expected: true},
+		{err: errors.New("down"), expected: false},
+		{expected: false},
+		{expected: true},
+	}
+
+	for i, step := range steps {
+		pinger.errs["backend:25565"] = step.err
+		healthy := checker.CheckAll(context.Background(), []string{"backend:25565"})
+		if healthy["backend:25565"] != step.expected {
+			t.Errorf("step %d: expected healthy %v, got %v", i, step.expected, healthy["backend:25565"])
+		}
+	}
+}
+
+func TestHealthCheckerProbe(t *testing.T) {
+	pinger := &mockPinger{errs: map[string]error{}}
+	checker := NewHealthChecker(pinger, 2)
+	checker.Concurrency = 1
+
+	// Probing never builds up a streak.
+	for range 3 {
+		if checker.Probe(context.Background(), []string{"backend:25565"})["backend:25565"] {
+			t.Fatal("expected a probe without a recorded success to be unhealthy")
+		}
+	}
+
+	checker.CheckAll(context.Background(), []string{"backend:25565"})
+	if !checker.Probe(context.Background(), []string{"backend:25565"})["backend:25565"] {
+		t.Error("expected a probe to report what the next check would")
+	}
+
+	pinger.errs["backend:25565"] = errors.New("down")
+	if checker.Probe(context.Background(), []string{"backend:25565"})["backend:25565"] {
+		t.Error("expected a failed probe to be unhealthy")
+	}
+
+	// The failed probe did not reset the recorded streak either.
+	pinger.errs["backend:25565"] = nil
+	if !checker.CheckAll(context.Background(), []string{"backend:25565"})["backend:25565"] {
+		t.Error("expected the second recorded success to be healthy")
+	}
+}
+
+func TestReconcilerPlanReadOnly(t *testing.T) {
+	sl := &mockServerList{routes: Routes{
+		{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+		{ServerAddress: "survival.example.com", Backend: "survival"},
+		{ServerAddress: "creative.example.com", Backend: "creative:25565"},
+		{ServerAddress: "creative.example.com", Backend: "creative-2:25565"},
+	}}
+	mr := &mockMcRouter{}
+	reconciler := NewReconciler(sl, mr, 30*time.Second)
+	reconciler.HealthChecker = NewHealthChecker(&mockPinger{errs: map[string]error{}}, 3)
+	reconciler.HealthChecker.Concurrency = 1
+
+	for range 3 {
+		if _, err := reconciler.Plan(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if status := reconciler.Status(); len(status.Rejected) != 0 || len(status.Duplicates) != 0 {
+		t.Errorf("expected Plan to leave the status alone, got %+v", status)
+	}
+
+	// Plans don't count towards the required successes.
+	if err := reconciler.Reconcile(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mr.registered) != 0 {
+		t.Errorf("expected routes to be held back after the first recorded check, got %v", mr.registered)
+	}
+	if status := reconciler.Status(); len(status.Rejected) != 1 || len(status.Duplicates) != 1 {
+		t.Errorf("expected Reconcile to record the rejected and duplicate entries, got %+v", status)
+	}
+}
+
+func TestReconcilerHealthCheck(t *testing.T) {
+	tests := []struct {
+		name              string
+		serverList        Routes
+		mcRouter          Routes
+		errs              map[string]error
+		removeUnhealthy   bool
+		cycles            int
+		expectedRegisters int
+		expectedDeletes   int
+	}{
+		{
+			name: "holds back route until enough successful pings",
+			serverList: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			cycles:            1,
+			expectedRegisters: 0,
+		},
+		{
+			name: "registers route after enough successful pings",
+			serverList: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			cycles:            2,
+			expectedRegisters: 1,
+		},
+		{
+			name: "never registers unhealthy backend",
+			serverList: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			errs:              map[string]error{"backend1:25565": errors.New("down")},
+			cycles:            3,
+			expectedRegisters: 0,
+		},
+		{
+			name: "keeps unhealthy route without remove unhealthy",
+			serverList: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			mcRouter: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			errs:            map[string]error{"backend1:25565": errors.New("down")},
+			cycles:          1,
+			expectedDeletes: 0,
+		},
+		{
+			name: "removes unhealthy route",
+			serverList: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			mcRouter: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			},
+			errs:            map[string]error{"backend1:25565": errors.New("down")},
+			removeUnhealthy: true,
+			cycles:          1,
+			expectedDeletes: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sl := &mockServerList{routes: tt.serverList}
+			mr := &mockMcRouter{routes: tt.mcRouter}
+
+			reconciler := NewReconciler(sl, mr, 30*time.Second)
+			reconciler.HealthChecker = NewHealthChecker(&mockPinger{errs: tt.errs}, 2)
+			reconciler.HealthChecker.Concurrency = 1
+			reconciler.RemoveUnhealthy = tt.removeUnhealthy
+
+			for range tt.cycles {
+				if err := reconciler.Reconcile(context.Background()); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if mr.registerCallCount != tt.expectedRegisters {
+				t.Errorf("expected %d registers, got %d", tt.expectedRegisters, mr.registerCallCount)
+			}
+			if mr.deleteCallCount != tt.expectedDeletes {
+				t.Errorf("expected %d deletes, got %d", tt.expectedDeletes, mr.deleteCallCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,584 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AnnotationServerAddress holds the comma separated server addresses
+	// routed to an annotated Service or Pod.
+	AnnotationServerAddress = "mc-router.seedloaf/server-address"
+	// AnnotationPort selects the port to route to, either a number or the
+	// name of a Service or container port.
+	AnnotationPort = "mc-router.seedloaf/port"
+
+	inClusterServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	kubernetesWatchTimeout     = 5 * time.Minute
+)
+
+var (
+	errWatchExpired = errors.New("watch resource version expired")
+)
+
+type KubernetesServerListOpts struct {
+	// APIServer is the base URL of the Kubernetes API, e.g.
+	// https://10.0.0.1:443.
+	APIServer string
+	// Token is sent as a bearer token. TokenFile takes precedence and is read
+	// on every request so rotated service account tokens are picked up.
+	Token     string
+	TokenFile string
+	// Client defaults to http.DefaultClient, set it to trust the cluster CA.
+	Client *http.Client
+	// Namespace limits discovery to one namespace, empty watches all of them.
+	Namespace     string
+	LabelSelector string
+	// Pods also discovers annotated Pods, routing to the pod IP. StatefulSet
+	// pods are discovered by annotating the pod template.
+	Pods bool
+}
+
+// KubernetesServerList discovers routes from Services, and optionally Pods,
+// annotated with AnnotationServerAddress. Watch keeps an in-memory copy of
+// the annotated objects up to date and signals Changes whenever their routes
+// change.
+type KubernetesServerList struct {
+	opts    KubernetesServerListOpts
+	client  *http.Client
+	changes chan struct{}
+
+	mu      sync.Mutex
+	objects map[string]Routes
+	synced  map[string]bool
+}
+
+// kubernetesResource describes how to build routes from one kind of object.
+type kubernetesResource struct {
+	name   string
+	routes func(data json.RawMessage) (key string, routes Routes, err error)
+}
+
+type k8sObjectMeta struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace"`
+	ResourceVersion   string            `json:"resourceVersion"`
+	Annotations       map[string]string `json:"annotations"`
+	DeletionTimestamp *string           `json:"deletionTimestamp"`
+}
+
+type k8sPort struct {
+	Name          string `json:"name"`
+	Port          int    `json:"port"`
+	ContainerPort int    `json:"containerPort"`
+}
+
+type k8sService struct {
+	Metadata k8sObjectMeta `json:"metadata"`
+	Spec     struct {
+		ClusterIP string    `json:"clusterIP"`
+		Ports     []k8sPort `json:"ports"`
+	} `json:"spec"`
+}
+
+type k8sPod struct {
+	Metadata k8sObjectMeta `json:"metadata"`
+	Spec     struct {
+		Containers []struct {
+			Ports []k8sPort `json:"ports"`
+		} `json:"containers"`
+	} `json:"spec"`
+	Status struct {
+		Phase string `json:"phase"`
+		PodIP string `json:"podIP"`
+	} `json:"status"`
+}
+
+type k8sList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []json.RawMessage `json:"items"`
+}
+
+type k8sWatchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+type k8sStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func NewKubernetesServerList(opts KubernetesServerListOpts) *KubernetesServerList {
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &KubernetesServerList{
+		opts:    opts,
+		client:  client,
+		changes: make(chan struct{}, 1),
+		objects: make(map[string]Routes),
+		synced:  make(map[string]bool),
+	}
+}
+
+// InClusterKubernetesOpts returns options for talking to the API server from
+// inside a pod, using its service account.
+func InClusterKubernetesOpts() (KubernetesServerListOpts, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return KubernetesServerListOpts{}, fmt.Errorf("not running in a cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+	}
+
+	ca, err := os.ReadFile(inClusterServiceAccountDir + "/ca.crt")
+	if err != nil {
+		return KubernetesServerListOpts{}, fmt.Errorf("failed to read cluster CA: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return KubernetesServerListOpts{}, fmt.Errorf("failed to parse cluster CA")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+
+	return KubernetesServerListOpts{
+		APIServer: "https://" + net.JoinHostPort(host, port),
+		TokenFile: inClusterServiceAccountDir + "/token",
+		Client:    &http.Client{Transport: transport},
+	}, nil
+}
+
+func (k *KubernetesServerList) GetServers() (Routes, error) {
+	return k.GetServersContext(context.Background())
+}
+
+// GetServersContext returns the routes seen by Watch. Until Watch has listed
+// every resource, the API server is listed directly.
+func (k *KubernetesServerList) GetServersContext(ctx context.Context) (Routes, error) {
+	if routes, ok := k.snapshot(); ok {
+		return routes, nil
+	}
+
+	var routes Routes
+	for _, res := range k.resources() {
+		_, objects, err := k.list(ctx, res)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, objectRoutesInOrder(objects)...)
+	}
+
+	sortRoutes(routes)
+	return routes, nil
+}
+
+// Changes is signalled whenever Watch sees the routes change.
+func (k *KubernetesServerList) Changes() <-chan struct{} {
+	return k.changes
+}
+
+// Watch lists and watches every resource until ctx is done, re-listing with
+// a backoff whenever a watch fails.
+func (k *KubernetesServerList) Watch(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, res := range k.resources() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			k.watchResource(ctx, res)
+		}()
+	}
+	wg.Wait()
+}
+
+func (k *KubernetesServerList) watchResource(ctx context.Context, res kubernetesResource) {
+	backoff := RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+
+	failures := 0
+	for {
+		listed, err := k.listAndWatch(ctx, res)
+		if ctx.Err() != nil {
+			return
+		}
+		if listed {
+			failures = 0
+		}
+
+		delay := time.Duration(0)
+		if errors.Is(err, errWatchExpired) {
+			slog.Debug("Kubernetes watch expired, re-listing", "resource", res.name)
+		} else {
+			failures++
+			delay = backoff.Backoff(failures)
+			slog.Warn("Kubernetes watch failed", "resource", res.name, "err", err, "retryIn", delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// listAndWatch reports whether the list succeeded along with the error that
+// ended the watch.
+func (k *KubernetesServerList) listAndWatch(ctx context.Context, res kubernetesResource) (bool, error) {
+	resourceVersion, objects, err := k.list(ctx, res)
+	if err != nil {
+		return false, err
+	}
+	k.replace(res, objects)
+
+	for {
+		resourceVersion, err = k.watch(ctx, res, resourceVersion)
+		if err != nil {
+			return true, err
+		}
+	}
+}
+
+func (k *KubernetesServerList) list(ctx context.Context, res kubernetesResource) (string, map[string]Routes, error) {
+	resp, err := k.get(ctx, res, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list %s: %w", res.name, err)
+	}
+	defer resp.Body.Close()
+
+	var list k8sList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return "", nil, fmt.Errorf("failed to decode %s list: %w", res.name, err)
+	}
+
+	objects := make(map[string]Routes)
+	for _, item := range list.Items {
+		key, routes, err := res.routes(item)
+		if err != nil {
+			slog.Warn("Skipping Kubernetes object", "resource", res.name, "key", key, "err", err)
+			continue
+		}
+		if len(routes) > 0 {
+			objects[key] = routes
+		}
+	}
+
+	return list.Metadata.ResourceVersion, objects, nil
+}
+
+// watch streams events from resourceVersion until the server closes the
+// watch, returning the last resource version seen.
+func (k *KubernetesServerList) watch(ctx context.Context, res kubernetesResource, resourceVersion string) (string, error) {
+	query := url.Values{}
+	query.Set("watch", "1")
+	query.Set("resourceVersion", resourceVersion)
+	query.Set("allowWatchBookmarks", "true")
+	query.Set("timeoutSeconds", strconv.Itoa(int(kubernetesWatchTimeout.Seconds())))
+
+	resp, err := k.get(ctx, res, query)
+	if err != nil {
+		return resourceVersion, fmt.Errorf("failed to watch %s: %w", res.name, err)
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event k8sWatchEvent
+		if err := decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) {
+				return resourceVersion, nil
+			}
+			return resourceVersion, fmt.Errorf("failed to decode %s watch event: %w", res.name, err)
+		}
+
+		if event.Type == "ERROR" {
+			var status k8sStatus
+			json.Unmarshal(event.Object, &status)
+			if status.Code == http.StatusGone {
+				return resourceVersion, errWatchExpired
+			}
+			return resourceVersion, fmt.Errorf("watch error: %s", status.Message)
+		}
+
+		var object struct {
+			Metadata k8sObjectMeta `json:"metadata"`
+		}
+		if err := json.Unmarshal(event.Object, &object); err != nil {
+			return resourceVersion, fmt.Errorf("failed to decode %s watch event: %w", res.name, err)
+		}
+		resourceVersion = object.Metadata.ResourceVersion
+
+		switch event.Type {
+		case "ADDED", "MODIFIED":
+			key, routes, err := res.routes(event.Object)
+			if key == "" {
+				continue
+			}
+			if err != nil {
+				slog.Warn("Skipping Kubernetes object", "resource", res.name, "key", key, "err", err)
+				routes = nil
+			}
+			k.set(key, routes)
+		case "DELETED":
+			k.set(res.name+"/"+object.Metadata.Namespace+"/"+object.Metadata.Name, nil)
+		}
+	}
+}
+
+func (k *KubernetesServerList) get(ctx context.Context, res kubernetesResource, query url.Values) (*http.Response, error) {
+	path := "/api/v1/" + res.name
+	if k.opts.Namespace != "" {
+		path = "/api/v1/namespaces/" + url.PathEscape(k.opts.Namespace) + "/" + res.name
+	}
+
+	if query == nil {
+		query = url.Values{}
+	}
+	if k.opts.LabelSelector != "" {
+		query.Set("labelSelector", k.opts.LabelSelector)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(k.opts.APIServer, "/")+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	token := k.opts.Token
+	if k.opts.TokenFile != "" {
+		data, err := os.ReadFile(k.opts.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return resp, nil
+}
+
+// replace swaps every object of res for a fresh list.
+func (k *KubernetesServerList) replace(res kubernetesResource, objects map[string]Routes) {
+	k.mu.Lock()
+	for key := range k.objects {
+		if strings.HasPrefix(key, res.name+"/") {
+			delete(k.objects, key)
+		}
+	}
+	for key, routes := range objects {
+		k.objects[key] = routes
+	}
+	k.synced[res.name] = true
+	k.mu.Unlock()
+
+	k.notify()
+}
+
+func (k *KubernetesServerList) set(key string, routes Routes) {
+	k.mu.Lock()
+	current := k.objects[key]
+	if len(routes) == 0 {
+		delete(k.objects, key)
+	} else {
+		k.objects[key] = routes
+	}
+	k.mu.Unlock()
+
+	// Pods are updated often, only routing changes trigger a reconcile.
+	if !reflect.DeepEqual(current, routes) && (len(current) > 0 || len(routes) > 0) {
+		k.notify()
+	}
+}
+
+func (k *KubernetesServerList) snapshot() (Routes, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for _, res := range k.resources() {
+		if !k.synced[res.name] {
+			return nil, false
+		}
+	}
+
+	routes := append(Routes{}, objectRoutesInOrder(k.objects)...)
+	sortRoutes(routes)
+
+	return routes, true
+}
+
+// objectRoutesInOrder flattens the routes of objects by object key, so that
+// objects claiming the same server address are always listed in the same
+// order.
+func objectRoutesInOrder(objects map[string]Routes) Routes {
+	var routes Routes
+	for _, key := range slices.Sorted(maps.Keys(objects)) {
+		routes = append(routes, objects[key]...)
+	}
+	return routes
+}
+
+func (k *KubernetesServerList) notify() {
+	select {
+	case k.changes <- struct{}{}:
+	default:
+	}
+}
+
+func (k *KubernetesServerList) resources() []kubernetesResource {
+	resources := []kubernetesResource{{name: "services", routes: serviceRoutes}}
+	if k.opts.Pods {
+		resources = append(resources, kubernetesResource{name: "pods", routes: podRoutes})
+	}
+	return resources
+}
+
+func serviceRoutes(data json.RawMessage) (string, Routes, error) {
+	var svc k8sService
+	if err := json.Unmarshal(data, &svc); err != nil {
+		return "", nil, err
+	}
+
+	key := "services/" + svc.Metadata.Namespace + "/" + svc.Metadata.Name
+	addresses := annotatedServerAddresses(svc.Metadata)
+	if len(addresses) == 0 {
+		return key, nil, nil
+	}
+
+	if svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == "None" {
+		return key, nil, fmt.Errorf("service has no cluster IP")
+	}
+
+	port, err := selectPort(svc.Metadata, svc.Spec.Ports, func(p k8sPort) int { return p.Port })
+	if err != nil {
+		return key, nil, err
+	}
+
+	return key, buildRoutes(addresses, svc.Spec.ClusterIP, port), nil
+}
+
+func podRoutes(data json.RawMessage) (string, Routes, error) {
+	var pod k8sPod
+	if err := json.Unmarshal(data, &pod); err != nil {
+		return "", nil, err
+	}
+
+	key := "pods/" + pod.Metadata.Namespace + "/" + pod.Metadata.Name
+	addresses := annotatedServerAddresses(pod.Metadata)
+	if len(addresses) == 0 {
+		return key, nil, nil
+	}
+
+	if pod.Status.Phase != "Running" || pod.Status.PodIP == "" || pod.Metadata.DeletionTimestamp != nil {
+		return key, nil, nil
+	}
+
+	var ports []k8sPort
+	for _, container := range pod.Spec.Containers {
+		ports = append(ports, container.Ports...)
+	}
+
+	port, err := selectPort(pod.Metadata, ports, func(p k8sPort) int { return p.ContainerPort })
+	if err != nil {
+		return key, nil, err
+	}
+
+	return key, buildRoutes(addresses, pod.Status.PodIP, port), nil
+}
+
+func annotatedServerAddresses(meta k8sObjectMeta) []string {
+	var addresses []string
+	for _, addr := range strings.Split(meta.Annotations[AnnotationServerAddress], ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addresses = append(addresses, addr)
+		}
+	}
+	return addresses
+}
+
+// selectPort picks the port named or numbered by AnnotationPort, otherwise
+// a port named "minecraft", the first port, or the Minecraft default.
+func selectPort(meta k8sObjectMeta, ports []k8sPort, number func(k8sPort) int) (int, error) {
+	if annotation := meta.Annotations[AnnotationPort]; annotation != "" {
+		if port, err := strconv.Atoi(annotation); err == nil {
+			return port, nil
+		}
+		for _, p := range ports {
+			if p.Name == annotation {
+				return number(p), nil
+			}
+		}
+		return 0, fmt.Errorf("port %q not found", annotation)
+	}
+
+	for _, p := range ports {
+		if p.Name == "minecraft" {
+			return number(p), nil
+		}
+	}
+	if len(ports) > 0 {
+		return number(ports[0]), nil
+	}
+
+	return defaultMinecraftPort, nil
+}
+
+func buildRoutes(addresses []string, ip string, port int) Routes {
+	backend := net.JoinHostPort(ip, strconv.Itoa(port))
+
+	routes := make(Routes, 0, len(addresses))
+	for _, addr := range addresses {
+		routes = append(routes, Route{ServerAddress: addr, Backend: backend})
+	}
+	return routes
+}
+
+// sortRoutes sorts routes by server address. Repeated addresses keep the
+// order they were listed in, which DuplicatePolicy depends on.
+func sortRoutes(routes Routes) {
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].ServerAddress < routes[j].ServerAddress
+	})
+}
@@ -0,0 +1,302 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeKubernetesAPI serves lists of objects and streams watch events sent on
+// the events channel of each resource.
+type fakeKubernetesAPI struct {
+	t       *testing.T
+	token   string
+	objects map[string][]any
+	events  map[string]chan string
+	paths   chan string
+}
+
+func newFakeKubernetesAPI(t *testing.T, objects map[string][]any) (*fakeKubernetesAPI, *httptest.Server) {
+	api := &fakeKubernetesAPI{
+		t:       t,
+		objects: objects,
+		events: map[string]chan string{
+			"services": make(chan string),
+			"pods":     make(chan string),
+		},
+		paths: make(chan string, 100),
+	}
+
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	return api, server
+}
+
+func (f *fakeKubernetesAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.paths <- r.URL.Path
+
+	if f.token != "" && r.Header.Get("Authorization") != "Bearer "+f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	resource := r.URL.Path[len(r.URL.Path)-len("services"):]
+	if resource != "services" {
+		resource = "pods"
+	}
+
+	if r.URL.Query().Get("watch") == "" {
+		json.NewEncoder(w).Encode(map[string]any{
+			"metadata": map[string]string{"resourceVersion": "1"},
+			"items":    f.objects[resource],
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-f.events[resource]:
+			w.Write([]byte(event + "\n"))
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+func testService(name string, annotations map[string]string, clusterIP string, ports ...map[string]any) map[string]any {
+	return map[string]any{
+		"metadata": map[string]any{"name": name, "namespace": "minecraft", "resourceVersion": "2", "annotations": annotations},
+		"spec":     map[string]any{"clusterIP": clusterIP, "ports": ports},
+	}
+}
+
+func testPod(name string, annotations map[string]string, phase, podIP string) map[string]any {
+	return map[string]any{
+		"metadata": map[string]any{"name": name, "namespace": "minecraft", "resourceVersion": "2", "annotations": annotations},
+		"spec": map[string]any{
+			"containers": []any{
+				map[string]any{"ports": []any{map[string]any{"name": "minecraft", "containerPort": 25565}}},
+			},
+		},
+		"status": map[string]any{"phase": phase, "podIP": podIP},
+	}
+}
+
+func TestKubernetesServerListGetServers(t *testing.T) {
+	tests := []struct {
+		name     string
+		objects  map[string][]any
+		pods     bool
+		expected Routes
+	}{
+		{
+			name: "annotated services",
+			objects: map[string][]any{
+				"services": {
+					testService("lobby", map[string]string{AnnotationServerAddress: "lobby.example.com"}, "10.0.0.1",
+						map[string]any{"name": "minecraft", "port": 25565}),
+					testService("survival", map[string]string{AnnotationServerAddress: "survival.example.com, smp.example.com", AnnotationPort: "rcon-less"}, "10.0.0.2",
+						map[string]any{"name": "metrics", "port": 9100}, map[string]any{"name": "rcon-less", "port": 25566}),
+					testService("numbered", map[string]string{AnnotationServerAddress: "numbered.example.com", AnnotationPort: "25570"}, "10.0.0.3"),
+					testService("unannotated", nil, "10.0.0.4", map[string]any{"port": 25565}),
+					testService("headless", map[string]string{AnnotationServerAddress: "headless.example.com"}, "None"),
+					testService("bad-port", map[string]string{AnnotationServerAddress: "bad.example.com", AnnotationPort: "missing"}, "10.0.0.5"),
+				},
+			},
+			expected: Routes{
+				{ServerAddress: "lobby.example.com", Backend: "10.0.0.1:25565"},
+				{ServerAddress: "numbered.example.com", Backend: "10.0.0.3:25570"},
+				{ServerAddress: "smp.example.com", Backend: "10.0.0.2:25566"},
+				{ServerAddress: "survival.example.com", Backend: "10.0.0.2:25566"},
+			},
+		},
+		{
+			name: "pods are ignored unless enabled",
+			objects: map[string][]any{
+				"pods": {
+					testPod("creative-0", map[string]string{AnnotationServerAddress: "creative.example.com"}, "Running", "10.1.0.1"),
+				},
+			},
+			expected: Routes{},
+		},
+		{
+			name: "running annotated pods",
+			objects: map[string][]any{
+				"pods": {
+					testPod("creative-0", map[string]string{AnnotationServerAddress: "creative.example.com"}, "Running", "10.1.0.1"),
+					testPod("pending-0", map[string]string{AnnotationServerAddress: "pending.example.com"}, "Pending", ""),
+				},
+			},
+			pods: true,
+			expected: Routes{
+				{ServerAddress: "creative.example.com", Backend: "10.1.0.1:25565"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, server := newFakeKubernetesAPI(t, tt.objects)
+
+			k := NewKubernetesServerList(KubernetesServerListOpts{
+				APIServer: server.URL,
+				Pods:      tt.pods,
+			})
+
+			routes, err := k.GetServers()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(routes) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, routes)
+			}
+			for i, route := range tt.expected {
+				if routes[i] != route {
+					t.Errorf("expected %v, got %v", route, routes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestKubernetesServerListSharedAddress(t *testing.T) {
+	_, server := newFakeKubernetesAPI(t, map[string][]any{
+		"services": {
+			testService("lobby-b", map[string]string{AnnotationServerAddress: "lobby.example.com"}, "10.0.0.2", map[string]any{"port": 25565}),
+			testService("lobby-a", map[string]string{AnnotationServerAddress: "lobby.example.com"}, "10.0.0.1", map[string]any{"port": 25565}),
+			testService("lobby-c", map[string]string{AnnotationServerAddress: "lobby.example.com"}, "10.0.0.3", map[string]any{"port": 25565}),
+		},
+	})
+
+	k := NewKubernetesServerList(KubernetesServerListOpts{APIServer: server.URL})
+
+	// Routes are ordered by object, so the duplicate policy always sees the
+	// Services in the same order.
+	expected := []string{"10.0.0.1:25565", "10.0.0.2:25565", "10.0.0.3:25565"}
+	check := func() {
+		t.Helper()
+		for range 50 {
+			routes, err := k.GetServers()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(routes) != len(expected) {
+				t.Fatalf("expected 3 routes, got %v", routes)
+			}
+			for i, backend := range expected {
+				if routes[i].Backend != backend {
+					t.Fatalf("expected backends in Service order %v, got %v", expected, routes)
+				}
+			}
+		}
+	}
+
+	// Listed without a watch.
+	check()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go k.Watch(ctx)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, synced := k.snapshot(); synced {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the watch to sync")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Served from the watch cache.
+	check()
+}
+
+func TestKubernetesServerListNamespaceAndToken(t *testing.T) {
+	api, server := newFakeKubernetesAPI(t, nil)
+	api.token = "service-account-token"
+
+	k := NewKubernetesServerList(KubernetesServerListOpts{
+		APIServer: server.URL,
+		Token:     "service-account-token",
+		Namespace: "minecraft",
+	})
+
+	if _, err := k.GetServers(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path := <-api.paths; path != "/api/v1/namespaces/minecraft/services" {
+		t.Errorf("expected namespaced path, got %s", path)
+	}
+
+	k.opts.Token = "wrong"
+	if _, err := k.GetServers(); err == nil {
+		t.Error("expected error with the wrong token")
+	}
+}
+
+func TestKubernetesServerListWatch(t *testing.T) {
+	api, server := newFakeKubernetesAPI(t, map[string][]any{
+		"services": {
+			testService("lobby", map[string]string{AnnotationServerAddress: "lobby.example.com"}, "10.0.0.1"),
+		},
+	})
+
+	k := NewKubernetesServerList(KubernetesServerListOpts{APIServer: server.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go k.Watch(ctx)
+
+	waitForChange := func() {
+		t.Helper()
+		select {
+		case <-k.Changes():
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for change")
+		}
+	}
+
+	expectRoutes := func(expected ...string) {
+		t.Helper()
+		routes, err := k.GetServers()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(routes) != len(expected) {
+			t.Fatalf("expected routes for %v, got %v", expected, routes)
+		}
+		for i, addr := range expected {
+			if routes[i].ServerAddress != addr {
+				t.Errorf("expected %s, got %s", addr, routes[i].ServerAddress)
+			}
+		}
+	}
+
+	waitForChange()
+	expectRoutes("lobby.example.com")
+
+	added, _ := json.Marshal(map[string]any{
+		"type":   "ADDED",
+		"object": testService("survival", map[string]string{AnnotationServerAddress: "survival.example.com"}, "10.0.0.2"),
+	})
+	api.events["services"] <- string(added)
+	waitForChange()
+	expectRoutes("lobby.example.com", "survival.example.com")
+
+	deleted, _ := json.Marshal(map[string]any{
+		"type":   "DELETED",
+		"object": testService("lobby", nil, "10.0.0.1"),
+	})
+	api.events["services"] <- string(deleted)
+	waitForChange()
+	expectRoutes("survival.example.com")
+}
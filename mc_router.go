@@ -1,6 +1,8 @@
 package mcrouterdiscovery
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,13 +11,17 @@ import (
 )
 
 type McRouterClient struct {
-	host   string
-	client *http.Client
-	auth   Auth
+	host        string
+	client      *http.Client
+	auth        Auth
+	retry       RetryPolicy
+	retryBudget *RetryBudget
 }
 
 type McRouterClientOpts struct {
-	Auth Auth
+	Auth        Auth
+	Retry       RetryPolicy
+	RetryBudget *RetryBudget
 }
 
 type GetResponse map[string]string
@@ -26,8 +32,10 @@ func (r *GetResponse) Parse(reader io.Reader) error {
 
 func NewMcRouterClient(host string, opts McRouterClientOpts) *McRouterClient {
 	return &McRouterClient{
-		host: host,
-		auth: opts.Auth,
+		host:        host,
+		auth:        opts.Auth,
+		retry:       opts.Retry,
+		retryBudget: opts.RetryBudget,
 		client: &http.Client{
 			Timeout: 15 * time.Second,
 		},
@@ -35,16 +43,18 @@ func NewMcRouterClient(host string, opts McRouterClientOpts) *McRouterClient {
 }
 
 func (c *McRouterClient) GetRoutes() (Routes, error) {
-	req, err := http.NewRequest(http.MethodGet, c.host+"/routes", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Accept", "application/json")
-	if c.auth != nil {
-		c.auth.AuthenticateRequest(req)
-	}
+	return c.GetRoutesContext(context.Background())
+}
 
-	resp, err := c.client.Do(req)
+func (c *McRouterClient) GetRoutesContext(ctx context.Context) (Routes, error) {
+	resp, err := c.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.host+"/routes", nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get routes: %w", err)
 	}
@@ -77,27 +87,54 @@ func ParseMappings(mappings map[string]string) Routes {
 }
 
 func (c *McRouterClient) RegisterRoute(route Route) error {
-	r, err := route.Json()
-	if err != nil {
-		return err
-	}
+	return c.RegisterRouteContext(context.Background(), route)
+}
 
-	req, err := http.NewRequest(http.MethodPost, c.host+"/routes", r)
+func (c *McRouterClient) RegisterRouteContext(ctx context.Context, route Route) error {
+	resp, err := c.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		r, err := route.Json()
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.host+"/routes", r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to register route: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if c.auth != nil {
-		c.auth.AuthenticateRequest(req)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
 	}
 
-	resp, err := c.client.Do(req)
+	return nil
+}
+
+func (c *McRouterClient) DeleteRoute(serverAddress string) error {
+	return c.DeleteRouteContext(context.Background(), serverAddress)
+}
+
+func (c *McRouterClient) DeleteRouteContext(ctx context.Context, serverAddress string) error {
+	resp, err := c.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, c.host+"/routes/"+serverAddress, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		return req, nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to register route: %w", err)
+		return fmt.Errorf("failed to delete route: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
 		body, _ := io.ReadAll(resp.Body)
 		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
 	}
@@ -105,22 +142,84 @@ func (c *McRouterClient) RegisterRoute(route Route) error {
 	return nil
 }
 
-func (c *McRouterClient) DeleteRoute(serverAddress string) error {
-	req, err := http.NewRequest(http.MethodDelete, c.host+"/routes/"+serverAddress, nil)
+func (c *McRouterClient) do(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	return doWithRetry(ctx, c.client, c.retry, c.retryBudget, func(ctx context.Context) (*http.Request, error) {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if c.auth != nil {
+			c.auth.AuthenticateRequest(req)
+		}
+		return req, nil
+	})
+}
+
+type defaultRouteBody struct {
+	Backend string `json:"backend"`
+}
+
+func (c *McRouterClient) GetDefaultRoute() (string, error) {
+	return c.GetDefaultRouteContext(context.Background())
+}
+
+// GetDefaultRouteContext returns the backend of mc-router's default route,
+// or ErrDefaultRouteUnknown if this mc-router version can't report it.
+func (c *McRouterClient) GetDefaultRouteContext(ctx context.Context) (string, error) {
+	resp, err := c.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.host+"/defaultRoute", nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		return req, nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to get default route: %w", err)
 	}
-	if c.auth != nil {
-		c.auth.AuthenticateRequest(req)
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return "", ErrDefaultRouteUnknown
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
 	}
 
-	resp, err := c.client.Do(req)
+	var body defaultRouteBody
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return body.Backend, nil
+}
+
+func (c *McRouterClient) SetDefaultRoute(backend string) error {
+	return c.SetDefaultRouteContext(context.Background(), backend)
+}
+
+func (c *McRouterClient) SetDefaultRouteContext(ctx context.Context, backend string) error {
+	body, err := json.Marshal(defaultRouteBody{Backend: backend})
 	if err != nil {
-		return fmt.Errorf("failed to delete route: %w", err)
+		return fmt.Errorf("failed to marshal default route: %w", err)
+	}
+
+	resp, err := c.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.host+"/defaultRoute", bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set default route: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
 		body, _ := io.ReadAll(resp.Body)
 		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
 	}
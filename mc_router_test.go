@@ -2,6 +2,7 @@ package mcrouterdiscovery
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -232,3 +233,100 @@ func TestDeleteRoute(t *testing.T) {
 		})
 	}
 }
+
+func TestGetDefaultRoute(t *testing.T) {
+	tests := []struct {
+		name            string
+		serverStatus    int
+		serverBody      string
+		expectedBackend string
+		expectedErr     error
+		expectError     bool
+	}{
+		{
+			name:            "default route set",
+			serverStatus:    http.StatusOK,
+			serverBody:      `{"backend": "lobby:25565"}`,
+			expectedBackend: "lobby:25565",
+		},
+		{
+			name:         "default route not set",
+			serverStatus: http.StatusOK,
+			serverBody:   `{}`,
+		},
+		{
+			name:         "not supported by mc router",
+			serverStatus: http.StatusMethodNotAllowed,
+			expectedErr:  ErrDefaultRouteUnknown,
+			expectError:  true,
+		},
+		{
+			name:         "server error",
+			serverStatus: http.StatusInternalServerError,
+			expectError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/defaultRoute" {
+					t.Errorf("expected path /defaultRoute, got %s", r.URL.Path)
+				}
+				if r.Method != http.MethodGet {
+					t.Errorf("expected method GET, got %s", r.Method)
+				}
+
+				w.WriteHeader(tt.serverStatus)
+				w.Write([]byte(tt.serverBody))
+			}))
+			defer server.Close()
+
+			client := NewMcRouterClient(server.URL, McRouterClientOpts{})
+			backend, err := client.GetDefaultRoute()
+
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				if tt.expectedErr != nil && !errors.Is(err, tt.expectedErr) {
+					t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if backend != tt.expectedBackend {
+				t.Errorf("expected backend %q, got %q", tt.expectedBackend, backend)
+			}
+		})
+	}
+}
+
+func TestSetDefaultRoute(t *testing.T) {
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/defaultRoute" {
+			t.Errorf("expected path /defaultRoute, got %s", r.URL.Path)
+		}
+		if r.Method != http.MethodPost {
+			t.Errorf("expected method POST, got %s", r.Method)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewMcRouterClient(server.URL, McRouterClientOpts{})
+	if err := client.SetDefaultRoute("lobby:25565"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if received["backend"] != "lobby:25565" {
+		t.Errorf("expected backend lobby:25565, got %v", received)
+	}
+}
@@ -0,0 +1,134 @@
+package mcrouterdiscovery
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics exposes reconcile activity in the Prometheus format. A nil
+// *Metrics is valid and records nothing.
+type Metrics struct {
+	reconciles        prometheus.Counter
+	reconcileDuration prometheus.Histogram
+	failures          *prometheus.CounterVec
+	actions           *prometheus.CounterVec
+	desiredRoutes     prometheus.Gauge
+	actualRoutes      prometheus.Gauge
+	lastSuccess       prometheus.Gauge
+	fallbackRoutes    prometheus.Gauge
+	rejectedRoutes    prometheus.Gauge
+}
+
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		reconciles: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "mc_router_sync_reconciles_total",
+			Help: "Number of reconcile cycles run.",
+		}),
+		reconcileDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "mc_router_sync_reconcile_duration_seconds",
+			Help:    "Duration of reconcile cycles.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mc_router_sync_reconcile_failures_total",
+			Help: "Number of failed reconcile cycles by the stage that failed.",
+		}, []string{"stage"}),
+		actions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mc_router_sync_actions_applied_total",
+			Help: "Number of actions successfully applied to mc-router by type.",
+		}, []string{"type"}),
+		desiredRoutes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mc_router_sync_desired_routes",
+			Help: "Number of routes in the server list during the last reconcile.",
+		}),
+		actualRoutes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mc_router_sync_actual_routes",
+			Help: "Number of routes in mc-router during the last reconcile.",
+		}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mc_router_sync_last_success_timestamp_seconds",
+			Help: "Unix timestamp of the last successful reconcile.",
+		}),
+		fallbackRoutes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mc_router_sync_fallback_routes",
+			Help: "Number of routes pointing at the fallback backend.",
+		}),
+		rejectedRoutes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "mc_router_sync_rejected_routes",
+			Help: "Number of server list entries rejected by validation in the last reconcile.",
+		}),
+	}
+
+	reg.MustRegister(
+		m.reconciles,
+		m.reconcileDuration,
+		m.failures,
+		m.actions,
+		m.desiredRoutes,
+		m.actualRoutes,
+		m.lastSuccess,
+		m.fallbackRoutes,
+		m.rejectedRoutes,
+	)
+
+	return m
+}
+
+func (m *Metrics) observeReconcile(duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	m.reconciles.Inc()
+	m.reconcileDuration.Observe(duration.Seconds())
+
+	if err == nil {
+		m.lastSuccess.SetToCurrentTime()
+		return
+	}
+
+	stage := StageUnknown
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		stage = stageErr.Stage
+	}
+	m.failures.WithLabelValues(string(stage)).Inc()
+}
+
+func (m *Metrics) observeRoutes(desired, actual int) {
+	if m == nil {
+		return
+	}
+
+	m.desiredRoutes.Set(float64(desired))
+	m.actualRoutes.Set(float64(actual))
+}
+
+func (m *Metrics) observeFallbacks(count int) {
+	if m == nil {
+		return
+	}
+
+	m.fallbackRoutes.Set(float64(count))
+}
+
+func (m *Metrics) observeRejected(count int) {
+	if m == nil {
+		return
+	}
+
+	m.rejectedRoutes.Set(float64(count))
+}
+
+func (m *Metrics) observeApply(result ApplyResult) {
+	if m == nil {
+		return
+	}
+
+	for _, succeeded := range result.Succeeded() {
+		m.actions.WithLabelValues(string(succeeded.Action.Type)).Inc()
+	}
+}
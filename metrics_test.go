@@ -0,0 +1,131 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func gatherMetric(t *testing.T, reg *prometheus.Registry, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if !labelsMatch(metric, labels) {
+				continue
+			}
+			switch {
+			case metric.Counter != nil:
+				return metric.Counter.GetValue()
+			case metric.Gauge != nil:
+				return metric.Gauge.GetValue()
+			case metric.Histogram != nil:
+				return float64(metric.Histogram.GetSampleCount())
+			}
+		}
+	}
+
+	return 0
+}
+
+func labelsMatch(metric *dto.Metric, labels map[string]string) bool {
+	for _, pair := range metric.GetLabel() {
+		if want, ok := labels[pair.GetName()]; ok && want != pair.GetValue() {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReconcilerMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	sl := &mockServerList{
+		routes: Routes{
+			{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+			{ServerAddress: "server2.example.com", Backend: "backend2:25565"},
+		},
+	}
+	mr := &mockMcRouter{
+		routes: Routes{
+			{ServerAddress: "server2.example.com", Backend: "old-backend2:25565"},
+			{ServerAddress: "server3.example.com", Backend: "backend3:25565"},
+		},
+	}
+
+	reconciler := NewReconciler(sl, mr, 30*time.Second)
+	reconciler.Metrics = NewMetrics(reg)
+
+	if err := reconciler.Reconcile(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mr.err = fmt.Errorf("connection refused")
+	reconciler.Reconcile(context.Background())
+
+	sl.err = fmt.Errorf("connection refused")
+	reconciler.Reconcile(context.Background())
+
+	expected := []struct {
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{name: "mc_router_sync_reconciles_total", value: 3},
+		{name: "mc_router_sync_reconcile_duration_seconds", value: 3},
+		{name: "mc_router_sync_reconcile_failures_total", labels: map[string]string{"stage": "fetch_routes"}, value: 1},
+		{name: "mc_router_sync_reconcile_failures_total", labels: map[string]string{"stage": "fetch_server_list"}, value: 1},
+		{name: "mc_router_sync_actions_applied_total", labels: map[string]string{"type": "add"}, value: 1},
+		{name: "mc_router_sync_actions_applied_total", labels: map[string]string{"type": "update"}, value: 1},
+		{name: "mc_router_sync_actions_applied_total", labels: map[string]string{"type": "delete"}, value: 1},
+		{name: "mc_router_sync_desired_routes", value: 2},
+		{name: "mc_router_sync_actual_routes", value: 2},
+	}
+
+	for _, e := range expected {
+		if got := gatherMetric(t, reg, e.name, e.labels); got != e.value {
+			t.Errorf("expected %s%v to be %v, got %v", e.name, e.labels, e.value, got)
+		}
+	}
+
+	if got := gatherMetric(t, reg, "mc_router_sync_last_success_timestamp_seconds", nil); got == 0 {
+		t.Error("expected last success timestamp to be set")
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	m.observeReconcile(time.Second, nil)
+	m.observeRoutes(1, 1)
+	m.observeApply(ApplyResult{})
+}
+
+func TestHealthHandlerServesMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	NewMetrics(reg)
+
+	rec := httptest.NewRecorder()
+	NewHealthHandler(HealthServerOpts{Gatherer: reg}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "mc_router_sync_reconciles_total") {
+		t.Errorf("expected metrics output to contain mc_router_sync_reconciles_total, got:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,195 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrRouteConflict = errors.New("route conflict")
+)
+
+// ConflictPolicy decides which route is kept when several sources claim the
+// same server address with different backends.
+type ConflictPolicy string
+
+const (
+	// ConflictFirstWins keeps the route of the source listed first.
+	ConflictFirstWins ConflictPolicy = "first-wins"
+	// ConflictPriority keeps the route of the source with the highest
+	// Priority, falling back to the order the sources are listed in.
+	ConflictPriority ConflictPolicy = "priority"
+	// ConflictFail fails the whole reconcile.
+	ConflictFail ConflictPolicy = "fail"
+)
+
+func GetConflictPolicy(s string) (ConflictPolicy, error) {
+	switch ConflictPolicy(s) {
+	case ConflictFirstWins, ConflictPriority, ConflictFail:
+		return ConflictPolicy(s), nil
+	default:
+		return "", fmt.Errorf("invalid conflict policy: %s (must be first-wins, priority or fail)", s)
+	}
+}
+
+// ServerListSource is one of the server lists merged by a MultiServerList.
+type ServerListSource struct {
+	// Name is recorded as the Source of every route from this server list.
+	Name       string
+	ServerList ContextServerList
+	// Priority is only used by ConflictPriority, higher wins.
+	Priority int
+}
+
+// MultiServerList merges the routes of several server lists. Every route
+// records the name of the source it came from, and conflicts are detected on
+// normalized server addresses. If any source fails, the
+// whole fetch fails so that a partial list can't cause deletes.
+type MultiServerList struct {
+	sources []ServerListSource
+	policy  ConflictPolicy
+	changes chan struct{}
+}
+
+func NewMultiServerList(policy ConflictPolicy, sources ...ServerListSource) *MultiServerList {
+	return &MultiServerList{
+		sources: sources,
+		policy:  policy,
+		changes: make(chan struct{}, 1),
+	}
+}
+
+func (m *MultiServerList) GetServers() (Routes, error) {
+	return m.GetServersContext(context.Background())
+}
+
+func (m *MultiServerList) GetServersContext(ctx context.Context) (Routes, error) {
+	results := make([]Routes, len(m.sources))
+	errs := make([]error, len(m.sources))
+
+	var wg sync.WaitGroup
+	for i, source := range m.sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			routes, err := source.ServerList.GetServersContext(ctx)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to get servers from %s: %w", source.Name, err)
+				return
+			}
+			// The slice may be the source's own, such as a cached list.
+			routes = slices.Clone(routes)
+			for j := range routes {
+				routes[j] = normalizeRoute(routes[j])
+				routes[j].Source = source.Name
+			}
+			results[i] = routes
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	// Sources are visited in the order that decides who wins a conflict.
+	order := make([]int, len(m.sources))
+	for i := range order {
+		order[i] = i
+	}
+	if m.policy == ConflictPriority {
+		sort.SliceStable(order, func(a, b int) bool {
+			return m.sources[order[a]].Priority > m.sources[order[b]].Priority
+		})
+	}
+
+	// Only conflicts between sources are resolved here. Entries repeated
+	// within a source are all kept for the reconciler's DuplicatePolicy.
+	var merged Routes
+	winners := make(map[string]Route)
+	backends := make(map[string][]string)
+	for _, i := range order {
+		for _, route := range results[i] {
+			key := route.ServerAddress
+			if route.Default {
+				key = "default route"
+			}
+
+			winner, claimed := winners[key]
+			if !claimed {
+				winners[key] = route
+			}
+			if !claimed || winner.Source == route.Source {
+				backends[key] = append(backends[key], route.Backend)
+				merged = append(merged, route)
+				continue
+			}
+
+			if slices.Contains(backends[key], route.Backend) {
+				continue
+			}
+
+			if m.policy == ConflictFail {
+				return nil, &StageError{Stage: StageValidate, Err: fmt.Errorf("%w: %s is claimed by %s (%s) and %s (%s)", ErrRouteConflict, key, winner.Source, winner.Backend, route.Source, route.Backend)}
+			}
+			slog.Warn("Route conflict, ignoring route", "serverAddress", key, "source", route.Source, "backend", route.Backend, "winner", winner.Source, "winnerBackend", winner.Backend)
+		}
+	}
+
+	sortRoutes(merged)
+	return merged, nil
+}
+
+// normalizeRoute normalizes the server address and backend of route, so that
+// sources spelling them differently are compared as the same route. Values
+// that don't validate are left for ValidateRoutes to reject.
+func normalizeRoute(route Route) Route {
+	if addr, err := NormalizeHostname(route.ServerAddress); err == nil && !route.Default {
+		route.ServerAddress = addr
+	}
+	if backend, err := NormalizeBackend(route.Backend); err == nil {
+		route.Backend = backend
+	}
+	return route
+}
+
+// Changes is signalled whenever Watch sees any source change.
+func (m *MultiServerList) Changes() <-chan struct{} {
+	return m.changes
+}
+
+// Watch forwards the change notifications of every source that has them
+// until ctx is done.
+func (m *MultiServerList) Watch(ctx context.Context) {
+	var cases []reflect.SelectCase
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	for _, source := range m.sources {
+		if notifier, ok := source.ServerList.(ChangeNotifier); ok {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(notifier.Changes())})
+		}
+	}
+
+	for {
+		chosen, _, ok := reflect.Select(cases)
+		if chosen == 0 {
+			return
+		}
+		if !ok {
+			// A closed channel would otherwise be selected forever.
+			cases = append(cases[:chosen], cases[chosen+1:]...)
+			continue
+		}
+
+		select {
+		case m.changes <- struct{}{}:
+		default:
+		}
+	}
+}
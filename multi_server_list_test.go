@@ -0,0 +1,210 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type notifyingServerList struct {
+	countingServerList
+	changes chan struct{}
+}
+
+func (n *notifyingServerList) Changes() <-chan struct{} {
+	return n.changes
+}
+
+func TestMultiServerListGetServers(t *testing.T) {
+	file := Routes{
+		{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+		{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+	}
+	// Addresses are compared normalized, so these still conflict.
+	kubernetes := Routes{
+		{ServerAddress: "creative.example.com", Backend: "10.0.0.3:25565"},
+		{ServerAddress: "Lobby.Example.com.", Backend: "10.0.0.1:25565"},
+		{ServerAddress: "survival.example.com", Backend: "Survival:25565"},
+	}
+
+	tests := []struct {
+		name        string
+		policy      ConflictPolicy
+		expectError error
+		expected    Routes
+	}{
+		{
+			name:   "first wins",
+			policy: ConflictFirstWins,
+			expected: Routes{
+				{ServerAddress: "creative.example.com", Backend: "10.0.0.3:25565", Source: "kubernetes"},
+				{ServerAddress: "lobby.example.com", Backend: "lobby:25565", Source: "file"},
+				{ServerAddress: "survival.example.com", Backend: "survival:25565", Source: "file"},
+			},
+		},
+		{
+			name:   "priority",
+			policy: ConflictPriority,
+			expected: Routes{
+				{ServerAddress: "creative.example.com", Backend: "10.0.0.3:25565", Source: "kubernetes"},
+				{ServerAddress: "lobby.example.com", Backend: "10.0.0.1:25565", Source: "kubernetes"},
+				{ServerAddress: "survival.example.com", Backend: "survival:25565", Source: "kubernetes"},
+			},
+		},
+		{
+			name:        "fail",
+			policy:      ConflictFail,
+			expectError: ErrRouteConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMultiServerList(tt.policy,
+				ServerListSource{Name: "file", ServerList: &countingServerList{routes: append(Routes{}, file...)}, Priority: 1},
+				ServerListSource{Name: "kubernetes", ServerList: &countingServerList{routes: append(Routes{}, kubernetes...)}, Priority: 2},
+			)
+
+			routes, err := m.GetServers()
+			if tt.expectError != nil {
+				if !errors.Is(err, tt.expectError) {
+					t.Fatalf("expected error %v, got %v", tt.expectError, err)
+				}
+				var stageErr *StageError
+				if !errors.As(err, &stageErr) || stageErr.Stage != StageValidate {
+					t.Errorf("expected a conflict to fail the validate stage, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(routes) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, routes)
+			}
+			for i, route := range tt.expected {
+				if routes[i] != route {
+					t.Errorf("expected %v, got %v", route, routes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReconcilerRouteConflictStage(t *testing.T) {
+	m := NewMultiServerList(ConflictFail,
+		ServerListSource{Name: "file", ServerList: &countingServerList{routes: Routes{{ServerAddress: "Lobby.example.com", Backend: "lobby:25565"}}}},
+		ServerListSource{Name: "api", ServerList: &countingServerList{routes: Routes{{ServerAddress: "lobby.example.com", Backend: "lobby2:25565"}}}},
+	)
+	reconciler := NewReconcilerContext(m, AdaptMcRouter(&mockMcRouter{}), 0)
+
+	_, err := reconciler.Diff(context.Background())
+	var stageErr *StageError
+	if !errors.As(err, &stageErr) || stageErr.Stage != StageValidate || !errors.Is(err, ErrRouteConflict) {
+		t.Errorf("expected a route conflict in the validate stage, got %v", err)
+	}
+}
+
+func TestMultiServerListDuplicatesWithinSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   DuplicatePolicy
+		expected string
+	}{
+		{name: "keep last", policy: DuplicateKeepLast, expected: "lobby2:25565"},
+		{name: "keep first", policy: DuplicateKeepFirst, expected: "lobby:25565"},
+		{name: "error", policy: DuplicateError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMultiServerList(ConflictFail,
+				ServerListSource{Name: "file", ServerList: &countingServerList{routes: Routes{{ServerAddress: "survival.example.com", Backend: "survival:25565"}}}},
+				ServerListSource{Name: "api", ServerList: &countingServerList{routes: Routes{
+					{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+					{ServerAddress: "Lobby.example.com", Backend: "lobby2:25565"},
+				}}},
+			)
+			mr := &mockMcRouter{}
+			reconciler := NewReconcilerContext(m, AdaptMcRouter(mr), 0)
+			reconciler.DuplicatePolicy = tt.policy
+
+			err := reconciler.Reconcile(context.Background())
+
+			duplicates := reconciler.Status().Duplicates
+			if len(duplicates) != 1 || len(duplicates[0].Backends) != 2 {
+				t.Fatalf("expected lobby.example.com to be reported with both backends, got %v", duplicates)
+			}
+
+			if tt.policy == DuplicateError {
+				if !errors.Is(err, ErrDuplicateRoute) {
+					t.Errorf("expected ErrDuplicateRoute, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, route := range mr.registered {
+				if route.ServerAddress == "lobby.example.com" && route.Backend != tt.expected {
+					t.Errorf("expected lobby.example.com to point at %s, got %s", tt.expected, route.Backend)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiServerListLeavesSourceRoutes(t *testing.T) {
+	cached := Routes{{ServerAddress: "Lobby.Example.com", Backend: "Lobby:25565"}}
+	m := NewMultiServerList(ConflictFirstWins,
+		ServerListSource{Name: "file", ServerList: &countingServerList{routes: cached}},
+		ServerListSource{Name: "api", ServerList: &countingServerList{}},
+	)
+
+	routes, err := m.GetServers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(routes) != 1 || routes[0].ServerAddress != "lobby.example.com" || routes[0].Source != "file" {
+		t.Errorf("expected the normalized route from file, got %v", routes)
+	}
+	if cached[0] != (Route{ServerAddress: "Lobby.Example.com", Backend: "Lobby:25565"}) {
+		t.Errorf("expected the source's routes to be left alone, got %v", cached[0])
+	}
+}
+
+func TestMultiServerListSourceError(t *testing.T) {
+	m := NewMultiServerList(ConflictFirstWins,
+		ServerListSource{Name: "file", ServerList: &countingServerList{routes: Routes{{ServerAddress: "lobby.example.com", Backend: "lobby:25565"}}}},
+		ServerListSource{Name: "api", ServerList: &countingServerList{err: errors.New("connection refused")}},
+	)
+
+	if _, err := m.GetServers(); err == nil {
+		t.Error("expected error when a source fails")
+	}
+}
+
+func TestMultiServerListWatch(t *testing.T) {
+	first := &notifyingServerList{changes: make(chan struct{}, 1)}
+	second := &notifyingServerList{changes: make(chan struct{}, 1)}
+	m := NewMultiServerList(ConflictFirstWins,
+		ServerListSource{Name: "file", ServerList: first},
+		ServerListSource{Name: "api", ServerList: &countingServerList{}},
+		ServerListSource{Name: "docker", ServerList: second},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Watch(ctx)
+
+	for _, source := range []*notifyingServerList{first, second} {
+		source.changes <- struct{}{}
+		select {
+		case <-m.Changes():
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for change")
+		}
+	}
+}
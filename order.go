@@ -0,0 +1,83 @@
+package mcrouterdiscovery
+
+import (
+	"fmt"
+	"path"
+	"sort"
+)
+
+// DeleteOrder decides whether deletes are applied before or after the rest
+// of a plan.
+type DeleteOrder string
+
+const (
+	// DeletesLast applies deletes after adds and updates, so a server moving
+	// to a new address is reachable on the new one before the old one goes.
+	// It is the default.
+	DeletesLast DeleteOrder = "last"
+	// DeletesFirst applies deletes before adds and updates.
+	DeletesFirst DeleteOrder = "first"
+)
+
+func GetDeleteOrder(s string) (DeleteOrder, error) {
+	switch DeleteOrder(s) {
+	case DeletesLast, DeletesFirst:
+		return DeleteOrder(s), nil
+	default:
+		return "", fmt.Errorf("invalid delete order: %s (must be first or last)", s)
+	}
+}
+
+// ValidatePriorityRoutes checks that every pattern is a valid path.Match
+// pattern.
+func ValidatePriorityRoutes(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid priority route pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// orderActions sorts actions into a deterministic plan. The default route is
+// set first, and deletes come first or last according to DeleteOrder. Within
+// each of those phases, actions for server addresses matching an earlier
+// PriorityRoutes pattern come first, ties are broken by server address.
+func (r *Reconciler) orderActions(actions []Action) []Action {
+	sort.SliceStable(actions, func(i, j int) bool {
+		a, b := actions[i], actions[j]
+		if pa, pb := r.phase(a), r.phase(b); pa != pb {
+			return pa < pb
+		}
+		if pa, pb := r.priority(a.ServerAddress), r.priority(b.ServerAddress); pa != pb {
+			return pa < pb
+		}
+		return a.ServerAddress < b.ServerAddress
+	})
+
+	return actions
+}
+
+func (r *Reconciler) phase(action Action) int {
+	switch {
+	case action.Type == ActionSetDefault:
+		return 0
+	case action.Type == ActionDelete && r.DeleteOrder == DeletesFirst:
+		return 1
+	case action.Type == ActionDelete:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// priority returns the index of the first PriorityRoutes pattern matching
+// serverAddress, or len(PriorityRoutes) if none does.
+func (r *Reconciler) priority(serverAddress string) int {
+	for i, pattern := range r.PriorityRoutes {
+		if ok, _ := path.Match(pattern, serverAddress); ok {
+			return i
+		}
+	}
+	return len(r.PriorityRoutes)
+}
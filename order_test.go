@@ -0,0 +1,117 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReconcilerPlanOrder(t *testing.T) {
+	serverList := Routes{
+		{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+		{ServerAddress: "creative.example.com", Backend: "creative-2:25565"},
+		{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+		{ServerAddress: "eu.lobby.example.com", Backend: "lobby-eu:25565"},
+		{ServerAddress: "arena.example.com", Backend: "arena:25565"},
+		{Default: true, Backend: "limbo:25565"},
+	}
+	mcRouter := Routes{
+		{ServerAddress: "creative.example.com", Backend: "creative:25565"},
+		{ServerAddress: "old.example.com", Backend: "old:25565"},
+		{ServerAddress: "old.lobby.example.com", Backend: "lobby-old:25565"},
+		{ServerAddress: "arena.example.com", Backend: "arena:25565"},
+	}
+
+	tests := []struct {
+		name           string
+		deleteOrder    DeleteOrder
+		priorityRoutes []string
+		expected       string
+	}{
+		{
+			name: "deletes last by default",
+			expected: `set default route: limbo:25565
+update creative.example.com: creative:25565 -> creative-2:25565
+add eu.lobby.example.com: lobby-eu:25565
+add lobby.example.com: lobby:25565
+add survival.example.com: survival:25565
+delete old.example.com: old:25565
+delete old.lobby.example.com: lobby-old:25565`,
+		},
+		{
+			name:        "deletes first",
+			deleteOrder: DeletesFirst,
+			expected: `set default route: limbo:25565
+delete old.example.com: old:25565
+delete old.lobby.example.com: lobby-old:25565
+update creative.example.com: creative:25565 -> creative-2:25565
+add eu.lobby.example.com: lobby-eu:25565
+add lobby.example.com: lobby:25565
+add survival.example.com: survival:25565`,
+		},
+		{
+			name:           "priority routes first",
+			priorityRoutes: []string{"lobby.example.com", "*.lobby.example.com"},
+			expected: `set default route: limbo:25565
+add lobby.example.com: lobby:25565
+add eu.lobby.example.com: lobby-eu:25565
+update creative.example.com: creative:25565 -> creative-2:25565
+add survival.example.com: survival:25565
+delete old.lobby.example.com: lobby-old:25565
+delete old.example.com: old:25565`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := &mockDefaultMcRouter{ContextMcRouter: AdaptMcRouter(&mockMcRouter{routes: mcRouter})}
+			reconciler := NewReconcilerContext(AdaptServerList(&mockServerList{routes: serverList}), mr, 0)
+			reconciler.DeleteOrder = tt.deleteOrder
+			reconciler.PriorityRoutes = tt.priorityRoutes
+
+			// Map iteration order differs between runs, so plan repeatedly.
+			for range 20 {
+				actions, err := reconciler.Plan(context.Background())
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if plan := FormatPlan(actions); plan != tt.expected {
+					t.Fatalf("expected plan:\n%s\ngot:\n%s", tt.expected, plan)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcilerDiffOrder(t *testing.T) {
+	reconciler := NewReconciler(&mockServerList{routes: Routes{
+		{ServerAddress: "c.example.com", Backend: "c:25565"},
+		{ServerAddress: "a.example.com", Backend: "a:25565"},
+	}}, &mockMcRouter{routes: Routes{
+		{ServerAddress: "d.example.com", Backend: "d:25565"},
+		{ServerAddress: "b.example.com", Backend: "b:25565"},
+	}}, 0)
+
+	diffs, err := reconciler.Diff(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"}
+	if len(diffs) != len(expected) {
+		t.Fatalf("expected %d diffs, got %d", len(expected), len(diffs))
+	}
+	for i, addr := range expected {
+		if diffs[i].ServerAddress != addr {
+			t.Errorf("expected diff %d to be %s, got %s", i, addr, diffs[i].ServerAddress)
+		}
+	}
+}
+
+func TestGetDeleteOrder(t *testing.T) {
+	if _, err := GetDeleteOrder("sometimes"); err == nil {
+		t.Error("expected error for an invalid delete order")
+	}
+	if err := ValidatePriorityRoutes([]string{"lobby.*", "[lobby"}); err == nil {
+		t.Error("expected error for an invalid pattern")
+	}
+}
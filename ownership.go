@@ -0,0 +1,218 @@
+package mcrouterdiscovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// OwnershipStore records which server addresses the reconciler registered in
+// mc-router, so that routes added by operators or other tools are left alone.
+type OwnershipStore interface {
+	Owned() (map[string]bool, error)
+	Claim(serverAddress string) error
+	Release(serverAddress string) error
+}
+
+// OwnershipBatcher is implemented by stores that can claim and release
+// several server addresses at once. The reconciler records the routes changed
+// by each phase of a plan with a single Update.
+type OwnershipBatcher interface {
+	Update(claims, releases []string) error
+}
+
+type MemoryOwnershipStore struct {
+	mu    sync.Mutex
+	owned map[string]bool
+}
+
+func NewMemoryOwnershipStore() *MemoryOwnershipStore {
+	return &MemoryOwnershipStore{
+		owned: make(map[string]bool),
+	}
+}
+
+func (s *MemoryOwnershipStore) Owned() (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]bool, len(s.owned))
+	for addr := range s.owned {
+		out[addr] = true
+	}
+
+	return out, nil
+}
+
+func (s *MemoryOwnershipStore) Claim(serverAddress string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.owned[serverAddress] = true
+	return nil
+}
+
+func (s *MemoryOwnershipStore) Update(claims, releases []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, serverAddress := range claims {
+		s.owned[serverAddress] = true
+	}
+	for _, serverAddress := range releases {
+		delete(s.owned, serverAddress)
+	}
+	return nil
+}
+
+func (s *MemoryOwnershipStore) Release(serverAddress string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.owned, serverAddress)
+	return nil
+}
+
+// FileOwnershipStore persists owned server addresses as a JSON array so that
+// ownership survives restarts.
+type FileOwnershipStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileOwnershipStore(path string) *FileOwnershipStore {
+	return &FileOwnershipStore{
+		path: path,
+	}
+}
+
+func (s *FileOwnershipStore) Owned() (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load()
+}
+
+func (s *FileOwnershipStore) Claim(serverAddress string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owned, err := s.load()
+	if err != nil {
+		return err
+	}
+	if owned[serverAddress] {
+		return nil
+	}
+
+	owned[serverAddress] = true
+	return s.save(owned)
+}
+
+func (s *FileOwnershipStore) Release(serverAddress string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owned, err := s.load()
+	if err != nil {
+		return err
+	}
+	if !owned[serverAddress] {
+		return nil
+	}
+
+	delete(owned, serverAddress)
+	return s.save(owned)
+}
+
+// Update claims and releases the server addresses with a single read and
+// write of the state file.
+func (s *FileOwnershipStore) Update(claims, releases []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owned, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, serverAddress := range claims {
+		if !owned[serverAddress] {
+			owned[serverAddress] = true
+			changed = true
+		}
+	}
+	for _, serverAddress := range releases {
+		if owned[serverAddress] {
+			delete(owned, serverAddress)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	return s.save(owned)
+}
+
+func (s *FileOwnershipStore) load() (map[string]bool, error) {
+	owned := make(map[string]bool)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return owned, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var addresses []string
+	if err := json.Unmarshal(data, &addresses); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+
+	for _, addr := range addresses {
+		owned[addr] = true
+	}
+
+	return owned, nil
+}
+
+func (s *FileOwnershipStore) save(owned map[string]bool) error {
+	addresses := make([]string, 0, len(owned))
+	for addr := range owned {
+		addresses = append(addresses, addr)
+	}
+	sort.Strings(addresses)
+
+	data, err := json.MarshalIndent(addresses, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated state file behind.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package mcrouterdiscovery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOwnershipStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) OwnershipStore{
+		"memory": func(t *testing.T) OwnershipStore {
+			return NewMemoryOwnershipStore()
+		},
+		"file": func(t *testing.T) OwnershipStore {
+			return NewFileOwnershipStore(filepath.Join(t.TempDir(), "state.json"))
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+
+			owned, err := store.Owned()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(owned) != 0 {
+				t.Errorf("expected no owned routes, got %v", owned)
+			}
+
+			if err := store.Claim("server1.example.com"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := store.Claim("server2.example.com"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := store.Release("server1.example.com"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := store.Release("unknown.example.com"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			owned, err = store.Owned()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(owned) != 1 || !owned["server2.example.com"] {
+				t.Errorf("expected only server2.example.com to be owned, got %v", owned)
+			}
+		})
+	}
+}
+
+func TestOwnershipStoresUpdate(t *testing.T) {
+	stores := map[string]func(t *testing.T) OwnershipStore{
+		"memory": func(t *testing.T) OwnershipStore {
+			return NewMemoryOwnershipStore()
+		},
+		"file": func(t *testing.T) OwnershipStore {
+			return NewFileOwnershipStore(filepath.Join(t.TempDir(), "state.json"))
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			batcher, ok := store.(OwnershipBatcher)
+			if !ok {
+				t.Fatalf("expected %T to implement OwnershipBatcher", store)
+			}
+
+			if err := store.Claim("server1.example.com"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err := batcher.Update(
+				[]string{"server2.example.com", "server3.example.com"},
+				[]string{"server1.example.com", "unknown.example.com"},
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			owned, err := store.Owned()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(owned) != 2 || !owned["server2.example.com"] || !owned["server3.example.com"] {
+				t.Errorf("expected server2.example.com and server3.example.com to be owned, got %v", owned)
+			}
+		})
+	}
+}
+
+func TestFileOwnershipStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	if err := NewFileOwnershipStore(path).Claim("server1.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	owned, err := NewFileOwnershipStore(path).Owned()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !owned["server1.example.com"] {
+		t.Errorf("expected server1.example.com to be owned after reload, got %v", owned)
+	}
+}
+
+func TestFileOwnershipStoreInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFileOwnershipStore(path).Owned(); err == nil {
+		t.Error("expected error but got none")
+	}
+}
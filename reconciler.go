@@ -2,8 +2,13 @@ package mcrouterdiscovery
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,10 +22,79 @@ type McRouter interface {
 	DeleteRoute(serverAddress string) error
 }
 
+// ChangeNotifier is implemented by server lists that can tell when their
+// routes changed, letting the reconciler sync without waiting for the next
+// tick.
+type ChangeNotifier interface {
+	Changes() <-chan struct{}
+}
+
+const DefaultTriggerDebounce = 250 * time.Millisecond
+
 type Reconciler struct {
-	ServerListClient ServerList
-	McRouterClient   McRouter
+	ServerListClient ContextServerList
+	McRouterClient   ContextMcRouter
 	Interval         time.Duration
+	// DryRun computes and logs the plan on each reconcile but never applies it.
+	DryRun bool
+	// Ownership records the routes this reconciler registered. When set, only
+	// owned routes are deleted unless PruneUnmanaged is enabled. A nil store
+	// deletes every route missing from the server list.
+	Ownership      OwnershipStore
+	PruneUnmanaged bool
+	DeletionGuard  DeletionGuard
+	// RetryBudget is shared with the clients and refilled at the start of
+	// every reconcile.
+	RetryBudget *RetryBudget
+	Metrics     *Metrics
+	// HealthChecker, when set, pings backends before routes to them are
+	// registered. Routes are held back until their backend is healthy, and
+	// with RemoveUnhealthy, routes to backends that stop answering are
+	// deleted.
+	HealthChecker   *HealthChecker
+	RemoveUnhealthy bool
+	// FallbackBackend, when set, is where routes point while their backend
+	// is unhealthy, and for up to FallbackGracePeriod after they disappear
+	// from the server list, instead of being held back or deleted. Routes
+	// are restored once their backend is healthy again.
+	FallbackBackend     string
+	FallbackGracePeriod time.Duration
+
+	// DuplicatePolicy decides which entry is used when the server list has
+	// several for the same server address, see DuplicateKeepLast.
+	DuplicatePolicy DuplicatePolicy
+
+	// DeleteOrder and PriorityRoutes order the actions of a plan, see
+	// orderActions. PriorityRoutes are path.Match patterns for server
+	// addresses, such as "lobby.*", that are applied first.
+	DeleteOrder    DeleteOrder
+	PriorityRoutes []string
+
+	// ApplyConcurrency is the number of actions Apply sends to mc-router at
+	// once, values below 2 apply them one by one. Above that, McRouterClient
+	// is called from several goroutines and must be safe for concurrent use.
+	// ApplyRateLimit caps the actions started per second, 0 disables it.
+	ApplyConcurrency int
+	ApplyRateLimit   float64
+
+	// Debounce is how long Start waits after a Trigger before reconciling,
+	// further triggers in that time are folded into the same reconcile.
+	Debounce time.Duration
+
+	// fallbacksMu guards fallbacks so Plan can run alongside a reconcile.
+	fallbacksMu sync.RWMutex
+	fallbacks   map[string]FallbackRoute
+	triggerOnce sync.Once
+	triggers    chan struct{}
+	reloadOnce  sync.Once
+	reloads     chan struct{}
+
+	// configMu guards ServerListClient, Interval, DeletionGuard and DryRun
+	// against Reload, SetDeletionGuard and SetDryRun.
+	configMu sync.RWMutex
+
+	statusMu sync.Mutex
+	status   Status
 }
 
 type ReconcilerDiff struct {
@@ -29,26 +103,130 @@ type ReconcilerDiff struct {
 	CurrentBackend string
 	InServerList   bool
 	InMcRouter     bool
+	Managed        bool
+	// Source is the server list the desired route came from, if known.
+	Source string
+	// Default marks the diff of mc-router's default route, ServerAddress is
+	// empty.
+	Default bool
+}
+
+type ReconcileStage string
+
+const (
+	StageFetchServerList ReconcileStage = "fetch_server_list"
+	StageValidate        ReconcileStage = "validate"
+	StageFetchRoutes     ReconcileStage = "fetch_routes"
+	StageOwnership       ReconcileStage = "ownership"
+	StageDeletionGuard   ReconcileStage = "deletion_guard"
+	StageApply           ReconcileStage = "apply"
+	StageUnknown         ReconcileStage = "unknown"
+)
+
+// StageError records which stage of a reconcile failed.
+type StageError struct {
+	Stage ReconcileStage
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
 }
 
 type ActionType string
 
 const (
-	ActionAdd    ActionType = "add"
-	ActionDelete ActionType = "delete"
+	ActionAdd        ActionType = "add"
+	ActionUpdate     ActionType = "update"
+	ActionDelete     ActionType = "delete"
+	ActionSetDefault ActionType = "set-default"
 )
 
+// Action is a change to mc-router. CurrentBackend is the backend mc-router
+// has before an update, delete or set-default.
 type Action struct {
-	Type          ActionType
-	ServerAddress string
-	Backend       string
+	Type           ActionType
+	ServerAddress  string
+	Backend        string
+	CurrentBackend string
+	// Source is the server list the desired route came from, if known.
+	Source string
+}
+
+func (a Action) String() string {
+	switch {
+	case a.Type == ActionUpdate:
+		return fmt.Sprintf("update %s: %s -> %s", a.ServerAddress, a.CurrentBackend, a.Backend)
+	case a.Type == ActionAdd:
+		return fmt.Sprintf("add %s: %s", a.ServerAddress, a.Backend)
+	case a.Type == ActionDelete:
+		return fmt.Sprintf("delete %s: %s", a.ServerAddress, a.CurrentBackend)
+	case a.Type == ActionSetDefault && a.CurrentBackend != "":
+		return fmt.Sprintf("set default route: %s -> %s", a.CurrentBackend, a.Backend)
+	case a.Type == ActionSetDefault:
+		return fmt.Sprintf("set default route: %s", a.Backend)
+	default:
+		return fmt.Sprintf("%s %s", a.Type, a.ServerAddress)
+	}
+}
+
+// pointsRoute reports whether the action registers a route to Backend.
+func (a Action) pointsRoute() bool {
+	return a.Type == ActionAdd || a.Type == ActionUpdate
+}
+
+// pointAt returns the action that points serverAddress at backend, an update
+// if mc-router already has it on currentBackend.
+func pointAt(serverAddress, backend, currentBackend string) Action {
+	action := Action{
+		Type:           ActionAdd,
+		ServerAddress:  serverAddress,
+		Backend:        backend,
+		CurrentBackend: currentBackend,
+	}
+	if currentBackend != "" {
+		action.Type = ActionUpdate
+	}
+	return action
+}
+
+// Plan is the list of actions returned by Reconciler.Plan.
+type Plan []Action
+
+// String renders the plan with FormatPlan.
+func (p Plan) String() string {
+	return FormatPlan(p)
+}
+
+// FormatPlan renders actions as a human-readable plan, one action per line.
+func FormatPlan(actions []Action) string {
+	if len(actions) == 0 {
+		return "no changes"
+	}
+
+	var b strings.Builder
+	for i, action := range actions {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(action.String())
+	}
+
+	return b.String()
 }
 
 func (r *Reconciler) Start(ctx context.Context) {
-	ticker := time.NewTicker(r.Interval)
+	ticker := time.NewTicker(r.interval())
 	defer ticker.Stop()
 
-	if err := r.Reconcile(); err != nil {
+	stopWatching := r.watchServerList(ctx)
+	defer func() { stopWatching() }()
+
+	if err := r.Reconcile(ctx); err != nil {
 		slog.Error("reconciliation error", "err", err)
 	}
 
@@ -58,44 +236,382 @@ func (r *Reconciler) Start(ctx context.Context) {
 			slog.Info("reconciler stopped")
 			return
 		case <-ticker.C:
-			if err := r.Reconcile(); err != nil {
+			if err := r.Reconcile(ctx); err != nil {
+				slog.Error("reconciliation error", "err", err)
+			}
+		case <-r.triggerChan():
+			if !r.debounce(ctx) {
+				slog.Info("reconciler stopped")
+				return
+			}
+			slog.Debug("reconcile triggered")
+			if err := r.Reconcile(ctx); err != nil {
 				slog.Error("reconciliation error", "err", err)
 			}
+			ticker.Reset(r.interval())
+		case <-r.reloadChan():
+			stopWatching()
+			stopWatching = r.watchServerList(ctx)
+			ticker.Reset(r.interval())
 		}
 	}
 }
 
-func (r *Reconciler) Reconcile() error {
-	diffs, err := r.Diff()
+// Reload swaps the server list and interval of a running reconciler and
+// triggers a reconcile. Ownership, health and fallback state carry over, so
+// routes already in mc-router are only touched where the new server list
+// disagrees with them. A nil server list or non-positive interval is refused
+// and the running config kept.
+func (r *Reconciler) Reload(serverList ContextServerList, interval time.Duration) error {
+	if serverList == nil {
+		return errors.New("failed to reload: server list is nil")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("failed to reload: interval must be positive, got %s", interval)
+	}
+
+	r.configMu.Lock()
+	r.ServerListClient = serverList
+	r.Interval = interval
+	r.configMu.Unlock()
+
+	select {
+	case r.reloadChan() <- struct{}{}:
+	default:
+	}
+	r.Trigger()
+	return nil
+}
+
+func (r *Reconciler) serverList() ContextServerList {
+	r.configMu.RLock()
+	defer r.configMu.RUnlock()
+
+	return r.ServerListClient
+}
+
+func (r *Reconciler) interval() time.Duration {
+	r.configMu.RLock()
+	defer r.configMu.RUnlock()
+
+	return r.Interval
+}
+
+// SetDeletionGuard swaps the deletion guard of a running reconciler, it
+// applies from the next reconcile.
+func (r *Reconciler) SetDeletionGuard(guard DeletionGuard) {
+	r.configMu.Lock()
+	defer r.configMu.Unlock()
+
+	r.DeletionGuard = guard
+}
+
+// SetDryRun turns dry run on or off for a running reconciler, it applies
+// from the next reconcile.
+func (r *Reconciler) SetDryRun(dryRun bool) {
+	r.configMu.Lock()
+	defer r.configMu.Unlock()
+
+	r.DryRun = dryRun
+}
+
+func (r *Reconciler) deletionGuard() DeletionGuard {
+	r.configMu.RLock()
+	defer r.configMu.RUnlock()
+
+	return r.DeletionGuard
+}
+
+func (r *Reconciler) dryRun() bool {
+	r.configMu.RLock()
+	defer r.configMu.RUnlock()
+
+	return r.DryRun
+}
+
+// watchServerList triggers a reconcile whenever the current server list
+// reports a change, until the returned func is called.
+func (r *Reconciler) watchServerList(ctx context.Context) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	if notifier, ok := r.serverList().(ChangeNotifier); ok {
+		r.TriggerOn(ctx, notifier.Changes())
+	}
+	return cancel
+}
+
+func (r *Reconciler) reloadChan() chan struct{} {
+	r.reloadOnce.Do(func() {
+		r.reloads = make(chan struct{}, 1)
+	})
+	return r.reloads
+}
+
+// Trigger asks Start to reconcile as soon as possible instead of waiting for
+// the next tick. It never blocks, triggers that arrive while one is already
+// pending are coalesced into a single reconcile.
+func (r *Reconciler) Trigger() {
+	select {
+	case r.triggerChan() <- struct{}{}:
+	default:
+	}
+}
+
+// TriggerOn calls Trigger for every signal received on ch until ch is closed
+// or ctx is done.
+func (r *Reconciler) TriggerOn(ctx context.Context, ch <-chan struct{}) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-ch:
+				if !ok {
+					return
+				}
+				r.Trigger()
+			}
+		}
+	}()
+}
+
+func (r *Reconciler) triggerChan() chan struct{} {
+	r.triggerOnce.Do(func() {
+		r.triggers = make(chan struct{}, 1)
+	})
+	return r.triggers
+}
+
+// debounce waits for Debounce so that a burst of triggers results in a single
+// reconcile. It returns false if ctx is done first.
+func (r *Reconciler) debounce(ctx context.Context) bool {
+	if r.Debounce > 0 {
+		timer := time.NewTimer(r.Debounce)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+
+	select {
+	case <-r.triggerChan():
+	default:
+	}
+	return true
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context) error {
+	start := time.Now()
+	err := r.reconcile(ctx)
+	r.Metrics.observeReconcile(time.Since(start), err)
+	r.recordResult(err)
+	return err
+}
+
+// Status returns the outcome of the most recent reconciles.
+func (r *Reconciler) Status() Status {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+
+	return r.status
+}
+
+func (r *Reconciler) recordRoutes(diffs []ReconcilerDiff) {
+	desired, actual := 0, 0
+	for _, diff := range diffs {
+		if diff.Default {
+			continue
+		}
+		if diff.InServerList {
+			desired++
+		}
+		if diff.InMcRouter {
+			actual++
+		}
+	}
+
+	r.Metrics.observeRoutes(desired, actual)
+
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+
+	r.status.DesiredRoutes = desired
+	r.status.ActualRoutes = actual
+}
+
+func (r *Reconciler) recordResult(err error) {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+
+	now := time.Now()
+	r.status.LastReconcile = now
+	r.status.DeletionGuardTripped = errors.Is(err, ErrTooManyDeletes)
+
+	var stageErr *StageError
+	errors.As(err, &stageErr)
+	switch {
+	case stageErr != nil && stageErr.Stage == StageFetchServerList:
+		// mc-router is not queried when the server list fails, so its
+		// reachability is left as it was.
+		r.status.ServerListReachable = false
+	case stageErr != nil && stageErr.Stage == StageValidate:
+		// The server list was fetched but rejected, mc-router is not
+		// queried either.
+		r.status.ServerListReachable = true
+	case stageErr != nil && stageErr.Stage == StageFetchRoutes:
+		r.status.ServerListReachable = true
+		r.status.McRouterReachable = false
+	default:
+		r.status.ServerListReachable = true
+		r.status.McRouterReachable = true
+	}
+
+	if err != nil {
+		r.status.LastError = err.Error()
+		return
+	}
+
+	r.status.LastSuccess = now
+	r.status.LastError = ""
+}
+
+func (r *Reconciler) reconcile(ctx context.Context) error {
+	r.RetryBudget.Reset()
+
+	diffs, issues, err := r.diff(ctx)
+	if issues != nil {
+		r.recordRejected(issues.rejected)
+		r.recordDuplicates(issues.duplicates)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to diff: %w", err)
 	}
 	slog.Debug("Reconciling diffs", "diffs", diffs)
+	r.recordRoutes(diffs)
+
+	actions, fallbacks := r.planActions(ctx, diffs, true)
+	r.recordFallbacks(fallbacks)
+	guardErr := r.checkDeletionGuard(actions, diffs)
+
+	if r.dryRun() {
+		slog.Info("Dry run, skipping apply", "actions", len(actions))
+		for _, action := range actions {
+			slog.Info("Planned action", "action", action.String(), "source", action.Source)
+		}
+		if guardErr != nil {
+			slog.Warn("Plan would be refused by the deletion guard", "err", guardErr)
+		}
+		return nil
+	}
+
+	if guardErr != nil {
+		slog.Error("REFUSING TO APPLY PLAN: deletion guard tripped, mc-router was not modified", "err", guardErr)
+		return &StageError{Stage: StageDeletionGuard, Err: guardErr}
+	}
+
+	r.claimInSync(diffs)
 
-	actions := r.Actions(diffs)
 	slog.Debug("Applying Actions", "actions", actions)
-	err = r.Apply(actions)
+	result, err := r.Apply(ctx, actions)
+	r.Metrics.observeApply(result)
+	for _, failed := range result.Failed() {
+		slog.Error("action failed", "action", failed.Action.String(), "source", failed.Action.Source, "err", failed.Err)
+	}
+	if len(result.Results) > 0 {
+		applied := make(map[ActionType]int)
+		for _, succeeded := range result.Succeeded() {
+			applied[succeeded.Action.Type]++
+		}
+		slog.Info("Applied plan", "adds", applied[ActionAdd], "updates", applied[ActionUpdate], "deletes", applied[ActionDelete], "failed", len(result.Failed()))
+	}
 	if err != nil {
-		return fmt.Errorf("failed to apply actions: %w", err)
+		return &StageError{
+			Stage: StageApply,
+			Err:   fmt.Errorf("failed to apply %d of %d actions: %w", len(result.Failed()), len(result.Results), err),
+		}
 	}
 
 	return nil
 }
 
-func (r *Reconciler) Diff() ([]ReconcilerDiff, error) {
-	serverListRoutes, err := r.ServerListClient.GetServers()
+// Plan diffs the server list against mc-router and returns the actions a
+// reconcile would apply, without applying or recording anything. Backends are
+// probed when a HealthChecker is set, see HealthChecker.Probe.
+func (r *Reconciler) Plan(ctx context.Context) (Plan, error) {
+	diffs, err := r.Diff(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff: %w", err)
+	}
+	slog.Debug("Reconciling diffs", "diffs", diffs)
+
+	actions, _ := r.planActions(ctx, diffs, false)
+	return actions, nil
+}
+
+// planActions turns diffs into the actions of a reconcile: routes to
+// unhealthy backends are held back or pointed at FallbackBackend, routes
+// missing from the server list stay on it for their grace period, and the
+// result is ordered. The fallbacks are returned for reconcile to record, and
+// health checks are only recorded with record.
+func (r *Reconciler) planActions(ctx context.Context, diffs []ReconcilerDiff, record bool) ([]Action, map[string]FallbackRoute) {
+	r.fallbacksMu.RLock()
+	defer r.fallbacksMu.RUnlock()
+
+	fallbacks := make(map[string]FallbackRoute)
+	actions := r.filterUnhealthy(ctx, diffs, r.Actions(diffs), fallbacks, record)
+	return r.orderActions(r.fallbackMissing(actions, fallbacks)), fallbacks
+}
+
+// Diff compares the server list with mc-router. The diffs are sorted by
+// server address, with the default route last. Rejected and duplicate server
+// list entries are only recorded in the status by Reconcile.
+func (r *Reconciler) Diff(ctx context.Context) ([]ReconcilerDiff, error) {
+	diffs, _, err := r.diff(ctx)
+	return diffs, err
+}
+
+// serverListIssues are the server list entries dropped by diff.
+type serverListIssues struct {
+	rejected   []RejectedRoute
+	duplicates []DuplicateRoute
+}
+
+// diff implements Diff. The issues are nil if the server list could not be
+// fetched, and returned alongside any later error.
+func (r *Reconciler) diff(ctx context.Context) ([]ReconcilerDiff, *serverListIssues, error) {
+	serverListRoutes, err := r.serverList().GetServersContext(ctx)
+	if err != nil {
+		// Server lists may fail a later stage themselves, such as
+		// MultiServerList on a route conflict.
+		var stageErr *StageError
+		if errors.As(err, &stageErr) {
+			return nil, nil, err
+		}
+		return nil, nil, &StageError{Stage: StageFetchServerList, Err: fmt.Errorf("failed to get servers: %w", err)}
+	}
+
+	issues := &serverListIssues{}
+	serverListRoutes, issues.rejected = ValidateRoutes(serverListRoutes)
+	serverListRoutes, issues.duplicates, err = r.checkDuplicates(serverListRoutes)
+	if err != nil {
+		return nil, issues, err
+	}
+	serverListRoutes, desiredDefault := splitDefaultRoute(serverListRoutes)
+
+	mcRouterRoutes, err := r.McRouterClient.GetRoutesContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get servers: %w", err)
+		return nil, issues, &StageError{Stage: StageFetchRoutes, Err: fmt.Errorf("failed to get routes: %w", err)}
 	}
 
-	mcRouterRoutes, err := r.McRouterClient.GetRoutes()
+	defaultDiff, err := r.diffDefaultRoute(ctx, desiredDefault)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get routes: %w", err)
+		return nil, issues, err
 	}
 
-	serverListMap := make(map[string]string)
+	serverListMap := make(map[string]Route)
 	for _, route := range serverListRoutes {
-		serverListMap[route.ServerAddress] = route.Backend
+		serverListMap[route.ServerAddress] = route
 	}
 
 	mcRouterMap := make(map[string]string)
@@ -103,6 +619,26 @@ func (r *Reconciler) Diff() ([]ReconcilerDiff, error) {
 		mcRouterMap[route.ServerAddress] = route.Backend
 	}
 
+	// Quarantined entries keep the route mc-router already has rather than
+	// it being deleted because of a typo in the backend.
+	for _, rejection := range issues.rejected {
+		addr := rejection.Route.ServerAddress
+		if _, ok := serverListMap[addr]; ok || !rejection.Quarantined || rejection.Route.Default {
+			continue
+		}
+		if current, ok := mcRouterMap[addr]; ok {
+			serverListMap[addr] = Route{ServerAddress: addr, Backend: current, Source: rejection.Route.Source}
+		}
+	}
+
+	owned := make(map[string]bool)
+	if r.Ownership != nil {
+		owned, err = r.Ownership.Owned()
+		if err != nil {
+			return nil, issues, &StageError{Stage: StageOwnership, Err: fmt.Errorf("failed to load owned routes: %w", err)}
+		}
+	}
+
 	allAddresses := make(map[string]bool)
 	for addr := range serverListMap {
 		allAddresses[addr] = true
@@ -112,67 +648,254 @@ func (r *Reconciler) Diff() ([]ReconcilerDiff, error) {
 	}
 
 	var diffs []ReconcilerDiff
-	for addr := range allAddresses {
-		desiredBackend, inServerList := serverListMap[addr]
+	for _, addr := range slices.Sorted(maps.Keys(allAddresses)) {
+		desired, inServerList := serverListMap[addr]
 		currentBackend, inMcRouter := mcRouterMap[addr]
 
 		diffs = append(diffs, ReconcilerDiff{
 			ServerAddress:  addr,
-			DesiredBackend: desiredBackend,
+			DesiredBackend: desired.Backend,
+			Source:         desired.Source,
 			CurrentBackend: currentBackend,
 			InServerList:   inServerList,
 			InMcRouter:     inMcRouter,
+			Managed:        owned[addr],
 		})
 	}
 
-	return diffs, nil
+	if defaultDiff != nil {
+		diffs = append(diffs, *defaultDiff)
+	}
+
+	return diffs, issues, nil
 }
 
 func (r *Reconciler) Actions(diffs []ReconcilerDiff) []Action {
 	var actions []Action
 
 	for _, diff := range diffs {
-		if (diff.InServerList && !diff.InMcRouter) || (diff.InServerList && diff.InMcRouter && diff.DesiredBackend != diff.CurrentBackend) {
+		if diff.Default {
+			if diff.InServerList && diff.DesiredBackend != diff.CurrentBackend {
+				actions = append(actions, Action{
+					Type:           ActionSetDefault,
+					Backend:        diff.DesiredBackend,
+					CurrentBackend: diff.CurrentBackend,
+				})
+			}
+			continue
+		}
+
+		if diff.InServerList && !diff.InMcRouter {
 			actions = append(actions, Action{
 				Type:          ActionAdd,
 				ServerAddress: diff.ServerAddress,
 				Backend:       diff.DesiredBackend,
+				Source:        diff.Source,
+			})
+		} else if diff.InServerList && diff.DesiredBackend != diff.CurrentBackend {
+			actions = append(actions, Action{
+				Type:           ActionUpdate,
+				ServerAddress:  diff.ServerAddress,
+				Backend:        diff.DesiredBackend,
+				CurrentBackend: diff.CurrentBackend,
+				Source:         diff.Source,
 			})
 		} else if !diff.InServerList && diff.InMcRouter {
+			if !r.mayDelete(diff) {
+				slog.Debug("Skipping delete of unmanaged route", "serverAddress", diff.ServerAddress)
+				continue
+			}
 			actions = append(actions, Action{
-				Type:          ActionDelete,
-				ServerAddress: diff.ServerAddress,
+				Type:           ActionDelete,
+				ServerAddress:  diff.ServerAddress,
+				CurrentBackend: diff.CurrentBackend,
 			})
 		}
 	}
 
-	return actions
+	return r.orderActions(actions)
 }
 
-func (r *Reconciler) Apply(actions []Action) error {
-	for _, action := range actions {
-		switch action.Type {
-		case ActionAdd:
-			route := Route{
-				ServerAddress: action.ServerAddress,
-				Backend:       action.Backend,
-			}
-			if err := r.McRouterClient.RegisterRoute(route); err != nil {
-				return fmt.Errorf("failed to register route %s: %w", action.ServerAddress, err)
-			}
-		case ActionDelete:
-			if err := r.McRouterClient.DeleteRoute(action.ServerAddress); err != nil {
-				return fmt.Errorf("failed to delete route %s: %w", action.ServerAddress, err)
-			}
+// Apply attempts every action, even after a failure, so that one bad backend
+// does not block the rest of the plan. The returned error joins all failures.
+// Results are in the same order as actions, however many were applied
+// concurrently, and actions not yet started when ctx is done are skipped.
+func (r *Reconciler) Apply(ctx context.Context, actions []Action) (ApplyResult, error) {
+	result := ApplyResult{
+		Results: make([]ActionResult, len(actions)),
+	}
+	limiter := newRateLimiter(r.ApplyRateLimit)
+
+	// Each run of actions in the same phase, see orderActions, is applied
+	// concurrently, and finishes before the next one starts.
+	for start := 0; start < len(actions); {
+		end := start + 1
+		for end < len(actions) && r.phase(actions[end]) == r.phase(actions[start]) {
+			end++
+		}
+		r.applyPhase(ctx, actions[start:end], result.Results[start:end], limiter)
+		start = end
+	}
+
+	return result, result.Err()
+}
+
+func (r *Reconciler) applyAction(ctx context.Context, action Action) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("skipped %s: %w", action.ServerAddress, err)
+	}
+
+	switch action.Type {
+	case ActionAdd, ActionUpdate:
+		// mc-router replaces the backend of a route that is registered again.
+		route := Route{
+			ServerAddress: action.ServerAddress,
+			Backend:       action.Backend,
 		}
+		if err := r.McRouterClient.RegisterRouteContext(ctx, route); err != nil {
+			return fmt.Errorf("failed to register route %s: %w", action.ServerAddress, err)
+		}
+	case ActionDelete:
+		if err := r.McRouterClient.DeleteRouteContext(ctx, action.ServerAddress); err != nil {
+			return fmt.Errorf("failed to delete route %s: %w", action.ServerAddress, err)
+		}
+	case ActionSetDefault:
+		return r.setDefaultRoute(ctx, action.Backend)
+	default:
+		return fmt.Errorf("unknown action type %q for %s", action.Type, action.ServerAddress)
 	}
+
 	return nil
 }
 
+func (r *Reconciler) checkDeletionGuard(actions []Action, diffs []ReconcilerDiff) error {
+	guard := r.deletionGuard()
+	if !guard.Enabled() {
+		return nil
+	}
+
+	currentRoutes := 0
+	for _, diff := range diffs {
+		if diff.InMcRouter && !diff.Default {
+			currentRoutes++
+		}
+	}
+
+	err := guard.Check(actions, currentRoutes)
+	if err != nil && guard.Override {
+		slog.Warn("Deletion guard overridden, applying plan anyway", "err", err)
+		return nil
+	}
+
+	return err
+}
+
+func (r *Reconciler) mayDelete(diff ReconcilerDiff) bool {
+	return r.Ownership == nil || r.PruneUnmanaged || diff.Managed
+}
+
+// claimInSync takes ownership of routes that already match the server list,
+// e.g. after the state was lost or the route was created before ownership
+// tracking was enabled.
+func (r *Reconciler) claimInSync(diffs []ReconcilerDiff) {
+	var claims []string
+	for _, diff := range diffs {
+		if !diff.Default && diff.InServerList && diff.InMcRouter && !diff.Managed && diff.DesiredBackend == diff.CurrentBackend {
+			claims = append(claims, diff.ServerAddress)
+		}
+	}
+	r.recordOwnership(claims, nil)
+}
+
+// recordOwnership claims and releases server addresses, in a single update
+// if the store is an OwnershipBatcher.
+func (r *Reconciler) recordOwnership(claims, releases []string) {
+	if r.Ownership == nil || len(claims)+len(releases) == 0 {
+		return
+	}
+
+	if batcher, ok := r.Ownership.(OwnershipBatcher); ok {
+		if err := batcher.Update(claims, releases); err != nil {
+			slog.Error("failed to record route ownership", "claims", claims, "releases", releases, "err", err)
+		}
+		return
+	}
+
+	for _, serverAddress := range claims {
+		r.claim(serverAddress)
+	}
+	for _, serverAddress := range releases {
+		r.release(serverAddress)
+	}
+}
+
+func (r *Reconciler) claim(serverAddress string) {
+	if r.Ownership == nil {
+		return
+	}
+	if err := r.Ownership.Claim(serverAddress); err != nil {
+		slog.Error("failed to record route ownership", "serverAddress", serverAddress, "err", err)
+	}
+}
+
+func (r *Reconciler) release(serverAddress string) {
+	if r.Ownership == nil {
+		return
+	}
+	if err := r.Ownership.Release(serverAddress); err != nil {
+		slog.Error("failed to release route ownership", "serverAddress", serverAddress, "err", err)
+	}
+}
+
+type ActionResult struct {
+	Action Action
+	Err    error
+}
+
+type ApplyResult struct {
+	Results []ActionResult
+}
+
+func (r ApplyResult) Succeeded() []ActionResult {
+	var out []ActionResult
+	for _, result := range r.Results {
+		if result.Err == nil {
+			out = append(out, result)
+		}
+	}
+	return out
+}
+
+func (r ApplyResult) Failed() []ActionResult {
+	var out []ActionResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			out = append(out, result)
+		}
+	}
+	return out
+}
+
+// Err joins the errors of every failed action, or returns nil if all succeeded.
+func (r ApplyResult) Err() error {
+	var errs []error
+	for _, result := range r.Failed() {
+		errs = append(errs, result.Err)
+	}
+	return errors.Join(errs...)
+}
+
+// NewReconciler creates a reconciler from context-unaware implementations,
+// see NewReconcilerContext for implementations that accept a context.
 func NewReconciler(sl ServerList, mr McRouter, interval time.Duration) *Reconciler {
+	return NewReconcilerContext(AdaptServerList(sl), AdaptMcRouter(mr), interval)
+}
+
+func NewReconcilerContext(sl ContextServerList, mr ContextMcRouter, interval time.Duration) *Reconciler {
 	return &Reconciler{
 		ServerListClient: sl,
 		McRouterClient:   mr,
 		Interval:         interval,
+		Debounce:         DefaultTriggerDebounce,
 	}
 }
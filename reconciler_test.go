@@ -23,6 +23,10 @@ type mockMcRouter struct {
 	deleteErr          error
 	registerErr        error
 	getRoutesCallCount int
+	registerCallCount  int
+	deleteCallCount    int
+	registered         Routes
+	deleted            []string
 }
 
 func (m *mockMcRouter) GetRoutes() (Routes, error) {
@@ -31,10 +35,14 @@ func (m *mockMcRouter) GetRoutes() (Routes, error) {
 }
 
 func (m *mockMcRouter) DeleteRoute(serverAddress string) error {
+	m.deleteCallCount++
+	m.deleted = append(m.deleted, serverAddress)
 	return m.deleteErr
 }
 
 func (m *mockMcRouter) RegisterRoute(route Route) error {
+	m.registerCallCount++
+	m.registered = append(m.registered, route)
 	return m.registerErr
 }
 
@@ -278,7 +286,7 @@ func TestReconcilerDiff(t *testing.T) {
 			}
 
 			reconciler := NewReconciler(sl, mr, 30*time.Second)
-			diffs, err := reconciler.Diff()
+			diffs, err := reconciler.Diff(context.Background())
 
 			if tt.expectError {
 				if err == nil {
@@ -367,7 +375,7 @@ func TestReconcilerActions(t *testing.T) {
 			},
 		},
 		{
-			name: "add action - route in both but different backends (update via add)",
+			name: "update action - route in both but different backends",
 			diffs: []ReconcilerDiff{
 				{
 					ServerAddress:  "server1.example.com",
@@ -379,7 +387,7 @@ func TestReconcilerActions(t *testing.T) {
 			},
 			expectedActions: []Action{
 				{
-					Type:          ActionAdd,
+					Type:          ActionUpdate,
 					ServerAddress: "server1.example.com",
 					Backend:       "new-backend:25565",
 				},
@@ -428,7 +436,7 @@ func TestReconcilerActions(t *testing.T) {
 					ServerAddress: "to-delete.example.com",
 				},
 				{
-					Type:          ActionAdd,
+					Type:          ActionUpdate,
 					ServerAddress: "to-update.example.com",
 					Backend:       "new-backend:25565",
 				},
@@ -500,7 +508,7 @@ func TestReconcilerActions(t *testing.T) {
 			},
 		},
 		{
-			name: "multiple updates (via add)",
+			name: "multiple updates",
 			diffs: []ReconcilerDiff{
 				{
 					ServerAddress:  "server1.example.com",
@@ -519,12 +527,12 @@ func TestReconcilerActions(t *testing.T) {
 			},
 			expectedActions: []Action{
 				{
-					Type:          ActionAdd,
+					Type:          ActionUpdate,
 					ServerAddress: "server1.example.com",
 					Backend:       "new-backend1:25565",
 				},
 				{
-					Type:          ActionAdd,
+					Type:          ActionUpdate,
 					ServerAddress: "server2.example.com",
 					Backend:       "new-backend2:25565",
 				},
@@ -572,18 +580,31 @@ func TestReconcilerActions(t *testing.T) {
 
 func TestReconcilerApply(t *testing.T) {
 	tests := []struct {
-		name        string
-		actions     []Action
-		registerErr error
-		deleteErr   error
-		expectError bool
-		errorMsg    string
+		name           string
+		actions        []Action
+		registerErr    error
+		deleteErr      error
+		expectError    bool
+		errorMsg       string
+		expectedFailed []string
 	}{
 		{
 			name:        "no actions",
 			actions:     []Action{},
 			expectError: false,
 		},
+		{
+			name: "successful update action",
+			actions: []Action{
+				{
+					Type:           ActionUpdate,
+					ServerAddress:  "server1.example.com",
+					Backend:        "new-backend:25565",
+					CurrentBackend: "old-backend:25565",
+				},
+			},
+			expectError: false,
+		},
 		{
 			name: "successful add action",
 			actions: []Action{
@@ -634,9 +655,10 @@ func TestReconcilerApply(t *testing.T) {
 					Backend:       "backend1:25565",
 				},
 			},
-			registerErr: fmt.Errorf("failed to register"),
-			expectError: true,
-			errorMsg:    "failed to register route",
+			registerErr:    fmt.Errorf("failed to register"),
+			expectError:    true,
+			errorMsg:       "failed to register route",
+			expectedFailed: []string{"server1.example.com"},
 		},
 		{
 			name: "delete route fails",
@@ -646,12 +668,13 @@ func TestReconcilerApply(t *testing.T) {
 					ServerAddress: "server1.example.com",
 				},
 			},
-			deleteErr:   fmt.Errorf("failed to delete"),
-			expectError: true,
-			errorMsg:    "failed to delete route",
+			deleteErr:      fmt.Errorf("failed to delete"),
+			expectError:    true,
+			errorMsg:       "failed to delete route",
+			expectedFailed: []string{"server1.example.com"},
 		},
 		{
-			name: "fails on first error in mixed actions",
+			name: "continues after error in mixed actions",
 			actions: []Action{
 				{
 					Type:          ActionAdd,
@@ -663,9 +686,34 @@ func TestReconcilerApply(t *testing.T) {
 					ServerAddress: "server2.example.com",
 				},
 			},
-			registerErr: fmt.Errorf("failed to register"),
-			expectError: true,
-			errorMsg:    "failed to register route",
+			registerErr:    fmt.Errorf("failed to register"),
+			expectError:    true,
+			errorMsg:       "failed to register route",
+			expectedFailed: []string{"server1.example.com"},
+		},
+		{
+			name: "reports every failure",
+			actions: []Action{
+				{
+					Type:          ActionAdd,
+					ServerAddress: "server1.example.com",
+					Backend:       "backend1:25565",
+				},
+				{
+					Type:          ActionDelete,
+					ServerAddress: "server2.example.com",
+				},
+				{
+					Type:          ActionAdd,
+					ServerAddress: "server3.example.com",
+					Backend:       "backend3:25565",
+				},
+			},
+			registerErr:    fmt.Errorf("failed to register"),
+			deleteErr:      fmt.Errorf("failed to delete"),
+			expectError:    true,
+			errorMsg:       "failed to delete route server2.example.com",
+			expectedFailed: []string{"server1.example.com", "server2.example.com", "server3.example.com"},
 		},
 	}
 
@@ -678,7 +726,24 @@ func TestReconcilerApply(t *testing.T) {
 			sl := &mockServerList{}
 
 			reconciler := NewReconciler(sl, mr, 30*time.Second)
-			err := reconciler.Apply(tt.actions)
+			result, err := reconciler.Apply(context.Background(), tt.actions)
+
+			if len(result.Results) != len(tt.actions) {
+				t.Errorf("expected a result for each of the %d actions, got %d", len(tt.actions), len(result.Results))
+			}
+			if mr.registerCallCount+mr.deleteCallCount != len(tt.actions) {
+				t.Errorf("expected every action to be attempted, got %d calls", mr.registerCallCount+mr.deleteCallCount)
+			}
+
+			failed := result.Failed()
+			if len(failed) != len(tt.expectedFailed) {
+				t.Errorf("expected %d failed actions, got %d", len(tt.expectedFailed), len(failed))
+			}
+			for i, addr := range tt.expectedFailed {
+				if i < len(failed) && failed[i].Action.ServerAddress != addr {
+					t.Errorf("expected failed action %d to be %s, got %s", i, addr, failed[i].Action.ServerAddress)
+				}
+			}
 
 			if tt.expectError {
 				if err == nil {
@@ -805,7 +870,7 @@ func TestReconcilerReconcile(t *testing.T) {
 			}
 
 			reconciler := NewReconciler(sl, mr, 30*time.Second)
-			err := reconciler.Reconcile()
+			err := reconciler.Reconcile(context.Background())
 
 			if tt.expectError {
 				if err == nil {
@@ -820,6 +885,254 @@ func TestReconcilerReconcile(t *testing.T) {
 	}
 }
 
+func TestReconcilerOwnership(t *testing.T) {
+	tests := []struct {
+		name            string
+		owned           []string
+		pruneUnmanaged  bool
+		expectedDeletes []string
+	}{
+		{
+			name:            "only owned routes are deleted",
+			owned:           []string{"owned.example.com"},
+			expectedDeletes: []string{"owned.example.com"},
+		},
+		{
+			name:            "nothing owned",
+			expectedDeletes: nil,
+		},
+		{
+			name:            "prune unmanaged deletes everything",
+			owned:           []string{"owned.example.com"},
+			pruneUnmanaged:  true,
+			expectedDeletes: []string{"owned.example.com", "manual.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryOwnershipStore()
+			for _, addr := range tt.owned {
+				store.Claim(addr)
+			}
+
+			sl := &mockServerList{
+				routes: Routes{},
+			}
+			mr := &mockMcRouter{
+				routes: Routes{
+					{ServerAddress: "owned.example.com", Backend: "backend1:25565"},
+					{ServerAddress: "manual.example.com", Backend: "backend2:25565"},
+				},
+			}
+
+			reconciler := NewReconciler(sl, mr, 30*time.Second)
+			reconciler.Ownership = store
+			reconciler.PruneUnmanaged = tt.pruneUnmanaged
+
+			actions, err := reconciler.Plan(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			deletes := make(map[string]bool)
+			for _, action := range actions {
+				if action.Type == ActionDelete {
+					deletes[action.ServerAddress] = true
+				}
+			}
+			if len(deletes) != len(tt.expectedDeletes) {
+				t.Errorf("expected deletes %v, got %v", tt.expectedDeletes, actions)
+			}
+			for _, addr := range tt.expectedDeletes {
+				if !deletes[addr] {
+					t.Errorf("expected %s to be deleted", addr)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcilerRecordsOwnership(t *testing.T) {
+	store := NewMemoryOwnershipStore()
+	store.Claim("gone.example.com")
+
+	sl := &mockServerList{
+		routes: Routes{
+			{ServerAddress: "new.example.com", Backend: "backend1:25565"},
+			{ServerAddress: "synced.example.com", Backend: "backend2:25565"},
+		},
+	}
+	mr := &mockMcRouter{
+		routes: Routes{
+			{ServerAddress: "synced.example.com", Backend: "backend2:25565"},
+			{ServerAddress: "gone.example.com", Backend: "backend3:25565"},
+		},
+	}
+
+	reconciler := NewReconciler(sl, mr, 30*time.Second)
+	reconciler.Ownership = store
+
+	if err := reconciler.Reconcile(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	owned, _ := store.Owned()
+	if !owned["new.example.com"] {
+		t.Error("expected registered route to be owned")
+	}
+	if !owned["synced.example.com"] {
+		t.Error("expected in sync route to be owned")
+	}
+	if owned["gone.example.com"] {
+		t.Error("expected deleted route to be released")
+	}
+}
+
+func TestReconcilerDeletionGuard(t *testing.T) {
+	newReconciler := func(guard DeletionGuard) (*Reconciler, *mockMcRouter) {
+		sl := &mockServerList{
+			routes: Routes{},
+		}
+		mr := &mockMcRouter{
+			routes: Routes{
+				{ServerAddress: "server1.example.com", Backend: "backend1:25565"},
+				{ServerAddress: "server2.example.com", Backend: "backend2:25565"},
+			},
+		}
+
+		reconciler := NewReconciler(sl, mr, 30*time.Second)
+		reconciler.DeletionGuard = guard
+		return reconciler, mr
+	}
+
+	t.Run("refuses plan over the limit", func(t *testing.T) {
+		reconciler, mr := newReconciler(DeletionGuard{MaxDeletes: 1})
+
+		err := reconciler.Reconcile(context.Background())
+		if !errors.Is(err, ErrTooManyDeletes) {
+			t.Fatalf("expected ErrTooManyDeletes, got %v", err)
+		}
+		if mr.deleteCallCount != 0 {
+			t.Errorf("expected no deletes, got %d", mr.deleteCallCount)
+		}
+		if !reconciler.Status().DeletionGuardTripped {
+			t.Error("expected status to report the tripped guard")
+		}
+	})
+
+	t.Run("override applies plan", func(t *testing.T) {
+		reconciler, mr := newReconciler(DeletionGuard{MaxDeletes: 1, Override: true})
+
+		if err := reconciler.Reconcile(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mr.deleteCallCount != 2 {
+			t.Errorf("expected 2 deletes, got %d", mr.deleteCallCount)
+		}
+		if reconciler.Status().DeletionGuardTripped {
+			t.Error("expected guard to not be reported as tripped")
+		}
+	})
+
+	t.Run("changed while running", func(t *testing.T) {
+		reconciler, mr := newReconciler(DeletionGuard{MaxDeletes: 1})
+
+		if err := reconciler.Reconcile(context.Background()); !errors.Is(err, ErrTooManyDeletes) {
+			t.Fatalf("expected ErrTooManyDeletes, got %v", err)
+		}
+
+		reconciler.SetDryRun(true)
+		reconciler.SetDeletionGuard(DeletionGuard{MaxDeletes: 1, Override: true})
+		if err := reconciler.Reconcile(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mr.deleteCallCount != 0 {
+			t.Errorf("expected no deletes in dry run, got %d", mr.deleteCallCount)
+		}
+
+		reconciler.SetDryRun(false)
+		if err := reconciler.Reconcile(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mr.deleteCallCount != 2 {
+			t.Errorf("expected 2 deletes once the guard is overridden, got %d", mr.deleteCallCount)
+		}
+	})
+}
+
+func TestReconcilerPlan(t *testing.T) {
+	sl := &mockServerList{
+		routes: Routes{
+			{ServerAddress: "new.example.com", Backend: "backend1:25565"},
+			{ServerAddress: "changed.example.com", Backend: "new-backend:25565"},
+		},
+	}
+	mr := &mockMcRouter{
+		routes: Routes{
+			{ServerAddress: "changed.example.com", Backend: "old-backend:25565"},
+			{ServerAddress: "gone.example.com", Backend: "backend3:25565"},
+		},
+	}
+
+	reconciler := NewReconciler(sl, mr, 30*time.Second)
+	actions, err := reconciler.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(actions) != 3 {
+		t.Fatalf("expected 3 actions, got %d", len(actions))
+	}
+	if mr.registerCallCount != 0 || mr.deleteCallCount != 0 {
+		t.Error("expected Plan to not modify mc router")
+	}
+
+	plan := FormatPlan(actions)
+	for _, line := range []string{
+		"add new.example.com: backend1:25565",
+		"update changed.example.com: old-backend:25565 -> new-backend:25565",
+		"delete gone.example.com: backend3:25565",
+	} {
+		if !contains(plan, line) {
+			t.Errorf("expected plan to contain %q, got:\n%s", line, plan)
+		}
+	}
+}
+
+func TestFormatPlanEmpty(t *testing.T) {
+	if plan := FormatPlan(nil); plan != "no changes" {
+		t.Errorf("expected 'no changes', got %q", plan)
+	}
+}
+
+func TestReconcilerDryRun(t *testing.T) {
+	sl := &mockServerList{
+		routes: Routes{
+			{ServerAddress: "new.example.com", Backend: "backend1:25565"},
+		},
+	}
+	mr := &mockMcRouter{
+		routes: Routes{
+			{ServerAddress: "gone.example.com", Backend: "backend2:25565"},
+		},
+	}
+
+	reconciler := NewReconciler(sl, mr, 30*time.Second)
+	reconciler.DryRun = true
+
+	if err := reconciler.Reconcile(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mr.registerCallCount != 0 {
+		t.Errorf("expected no register calls in dry run, got %d", mr.registerCallCount)
+	}
+	if mr.deleteCallCount != 0 {
+		t.Errorf("expected no delete calls in dry run, got %d", mr.deleteCallCount)
+	}
+}
+
 func TestReconcilerStart(t *testing.T) {
 	t.Run("stops on context cancellation", func(t *testing.T) {
 		sl := &mockServerList{
@@ -872,3 +1185,123 @@ func TestReconcilerStart(t *testing.T) {
 		}
 	})
 }
+
+func TestReconcilerTrigger(t *testing.T) {
+	t.Run("coalesces a burst of triggers", func(t *testing.T) {
+		mr := &mockMcRouter{routes: Routes{}}
+		reconciler := NewReconciler(&mockServerList{routes: Routes{}}, mr, time.Hour)
+		reconciler.Debounce = 50 * time.Millisecond
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			for range 10 {
+				reconciler.Trigger()
+			}
+		}()
+
+		reconciler.Start(ctx)
+
+		// One reconcile on start and one for the burst.
+		if mr.getRoutesCallCount != 2 {
+			t.Errorf("expected 2 reconciles, got %d", mr.getRoutesCallCount)
+		}
+	})
+
+	t.Run("triggers from a channel", func(t *testing.T) {
+		mr := &mockMcRouter{routes: Routes{}}
+		reconciler := NewReconciler(&mockServerList{routes: Routes{}}, mr, time.Hour)
+		reconciler.Debounce = 0
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+
+		ch := make(chan struct{})
+		reconciler.TriggerOn(ctx, ch)
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			ch <- struct{}{}
+			time.Sleep(50 * time.Millisecond)
+			ch <- struct{}{}
+		}()
+
+		reconciler.Start(ctx)
+
+		if mr.getRoutesCallCount != 3 {
+			t.Errorf("expected 3 reconciles, got %d", mr.getRoutesCallCount)
+		}
+	})
+}
+
+func TestReconcilerReload(t *testing.T) {
+	mr := &mockMcRouter{routes: Routes{{ServerAddress: "lobby.example.com", Backend: "lobby:25565"}}}
+	reconciler := NewReconciler(&mockServerList{routes: Routes{
+		{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+	}}, mr, time.Hour)
+	reconciler.Debounce = 0
+	reconciler.Ownership = NewMemoryOwnershipStore()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	// The new server list reports changes, which must trigger reconciles.
+	notifier := &notifyingServerList{changes: make(chan struct{}, 1)}
+	notifier.routes = Routes{{ServerAddress: "survival.example.com", Backend: "survival:25565"}}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		if err := reconciler.Reload(notifier, 50*time.Millisecond); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		notifier.changes <- struct{}{}
+	}()
+
+	reconciler.Start(ctx)
+
+	if notifier.calls < 3 {
+		t.Errorf("expected the new server list to be fetched on reload, on change and every interval, got %d fetches", notifier.calls)
+	}
+	if reconciler.Interval != 50*time.Millisecond {
+		t.Errorf("expected Interval to be 50ms, got %s", reconciler.Interval)
+	}
+	// The route claimed from the old server list is still owned, so it is
+	// deleted once the new server list no longer has it.
+	if len(mr.deleted) == 0 || mr.deleted[0] != "lobby.example.com" {
+		t.Errorf("expected lobby.example.com to be deleted, got %v", mr.deleted)
+	}
+}
+
+func TestReconcilerReloadInvalid(t *testing.T) {
+	sl := &mockServerList{routes: Routes{{ServerAddress: "lobby.example.com", Backend: "lobby:25565"}}}
+	mr := &mockMcRouter{}
+	reconciler := NewReconciler(sl, mr, time.Minute)
+
+	if err := reconciler.Reload(nil, time.Second); err == nil {
+		t.Fatal("expected an error reloading a nil server list")
+	}
+	if err := reconciler.Reload(AdaptServerList(sl), 0); err == nil {
+		t.Fatal("expected an error reloading a zero interval")
+	}
+	if reconciler.Interval != time.Minute {
+		t.Errorf("expected Interval to stay 1m, got %s", reconciler.Interval)
+	}
+
+	// The running server list is still used.
+	if err := reconciler.Reconcile(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mr.registered) != 1 {
+		t.Errorf("expected the running server list's route to be registered, got %v", mr.registered)
+	}
+}
+
+func TestPointAt(t *testing.T) {
+	if action := pointAt("lobby.example.com", "maintenance:25565", ""); action.Type != ActionAdd {
+		t.Errorf("expected an add for a route mc-router doesn't have, got %s", action)
+	}
+	if action := pointAt("lobby.example.com", "maintenance:25565", "lobby:25565"); action.Type != ActionUpdate || action.String() != "update lobby.example.com: lobby:25565 -> maintenance:25565" {
+		t.Errorf("expected an update for a route mc-router has, got %s", action)
+	}
+}
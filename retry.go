@@ -0,0 +1,166 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how HTTP calls to mc-router and the server list API are
+// retried on transient failures such as refused connections, timeouts and 5xx
+// responses. A MaxAttempts of 1 or less disables retries.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Multiplier grows the backoff after each attempt, defaults to 2.
+	Multiplier float64
+	// Jitter randomly shortens each backoff by up to this fraction (0-1).
+	Jitter float64
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Backoff returns the delay before the given retry, where retry 1 is the
+// delay after the first failed attempt.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		delay -= delay * p.Jitter * rand.Float64()
+	}
+
+	return time.Duration(delay)
+}
+
+// RetryBudget caps the number of retries shared by all clients during a
+// single reconcile cycle, so a dead dependency can't stall the cycle with
+// backoffs. A nil budget is unlimited.
+type RetryBudget struct {
+	mu        sync.Mutex
+	max       int
+	remaining int
+}
+
+func NewRetryBudget(max int) *RetryBudget {
+	return &RetryBudget{
+		max:       max,
+		remaining: max,
+	}
+}
+
+// Reset refills the budget, it is called at the start of every reconcile.
+func (b *RetryBudget) Reset() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.remaining = b.max
+}
+
+func (b *RetryBudget) Remaining() int {
+	if b == nil {
+		return math.MaxInt
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.remaining
+}
+
+func (b *RetryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.remaining <= 0 {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+// doWithRetry sends the request built by newRequest, retrying transient
+// failures according to policy. newRequest is called for every attempt so
+// request bodies can be replayed. Retries stop as soon as ctx is done.
+func doWithRetry(ctx context.Context, client *http.Client, policy RetryPolicy, budget *RetryBudget, newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil || !isRetryable(resp, err) {
+			return resp, err
+		}
+
+		if !budget.take() {
+			slog.Warn("retry budget exhausted", "method", req.Method, "url", req.URL.String())
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		delay := policy.Backoff(attempt)
+		slog.Debug("retrying request", "method", req.Method, "url", req.URL.String(), "attempt", attempt, "delay", delay, "err", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return true
+		}
+
+		return errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF)
+	}
+
+	return resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+}
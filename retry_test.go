@@ -0,0 +1,186 @@
+package mcrouterdiscovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     300 * time.Millisecond,
+		Multiplier:     2,
+	}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, want := range expected {
+		if got := policy.Backoff(i + 1); got != want {
+			t.Errorf("expected backoff %d to be %s, got %s", i+1, want, got)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := policy.Backoff(1)
+		if got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("expected jittered backoff between 50ms and 100ms, got %s", got)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	budget := NewRetryBudget(2)
+
+	if !budget.take() || !budget.take() {
+		t.Fatal("expected budget to allow 2 retries")
+	}
+	if budget.take() {
+		t.Error("expected budget to be exhausted")
+	}
+
+	budget.Reset()
+	if budget.Remaining() != 2 {
+		t.Errorf("expected 2 remaining after reset, got %d", budget.Remaining())
+	}
+
+	var unlimited *RetryBudget
+	if !unlimited.take() {
+		t.Error("expected nil budget to be unlimited")
+	}
+}
+
+func TestClientsRetryTransientFailures(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int32
+		failureStatus int
+		policy        RetryPolicy
+		budget        *RetryBudget
+		expectError   bool
+		expectedCalls int32
+	}{
+		{
+			name:          "recovers after 5xx",
+			failures:      2,
+			failureStatus: http.StatusServiceUnavailable,
+			policy:        testRetryPolicy(),
+			expectedCalls: 3,
+		},
+		{
+			name:          "gives up after max attempts",
+			failures:      5,
+			failureStatus: http.StatusBadGateway,
+			policy:        testRetryPolicy(),
+			expectError:   true,
+			expectedCalls: 3,
+		},
+		{
+			name:          "does not retry 4xx",
+			failures:      1,
+			failureStatus: http.StatusBadRequest,
+			policy:        testRetryPolicy(),
+			expectError:   true,
+			expectedCalls: 1,
+		},
+		{
+			name:          "retries disabled",
+			failures:      1,
+			failureStatus: http.StatusInternalServerError,
+			policy:        RetryPolicy{},
+			expectError:   true,
+			expectedCalls: 1,
+		},
+		{
+			name:          "stops when budget is exhausted",
+			failures:      5,
+			failureStatus: http.StatusInternalServerError,
+			policy:        testRetryPolicy(),
+			budget:        NewRetryBudget(1),
+			expectError:   true,
+			expectedCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= tt.failures {
+					w.WriteHeader(tt.failureStatus)
+					return
+				}
+				w.Write([]byte("[]"))
+			}))
+			defer server.Close()
+
+			client := NewServerListClientWithOpts(server.URL, &mockAuth{}, ServerListClientOpts{
+				Retry:       tt.policy,
+				RetryBudget: tt.budget,
+			})
+			_, err := client.GetServers()
+
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if calls.Load() != tt.expectedCalls {
+				t.Errorf("expected %d calls, got %d", tt.expectedCalls, calls.Load())
+			}
+		})
+	}
+}
+
+func TestMcRouterClientRetriesRegisterWithBody(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var route Route
+		if err := json.NewDecoder(r.Body).Decode(&route); err != nil || route.ServerAddress != "server1.example.com" {
+			t.Errorf("expected the route body on every attempt, got %+v (%v)", route, err)
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := NewMcRouterClient(server.URL, McRouterClientOpts{Retry: testRetryPolicy()})
+	err := client.RegisterRoute(Route{ServerAddress: "server1.example.com", Backend: "backend1:25565"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 calls, got %d", calls.Load())
+	}
+}
+
+func TestRetryConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	budget := NewRetryBudget(10)
+	client := NewMcRouterClient(url, McRouterClientOpts{Retry: testRetryPolicy(), RetryBudget: budget})
+	if _, err := client.GetRoutes(); err == nil {
+		t.Fatal("expected error but got none")
+	}
+
+	if budget.Remaining() != 8 {
+		t.Errorf("expected 2 retries to be taken from the budget, %d remaining", budget.Remaining())
+	}
+}
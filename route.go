@@ -8,8 +8,14 @@ import (
 )
 
 type Route struct {
-	ServerAddress string `json:"serverAddress"`
-	Backend       string `json:"backend"`
+	ServerAddress string `json:"serverAddress" yaml:"serverAddress"`
+	Backend       string `json:"backend" yaml:"backend"`
+	// Default declares Backend as mc-router's default route, used for
+	// connections that match no server address. ServerAddress is ignored.
+	Default bool `json:"default,omitempty" yaml:"default,omitempty"`
+	// Source names the server list the route came from when several are
+	// merged, it is only used for logging.
+	Source string `json:"-" yaml:"-"`
 }
 
 type Routes []Route
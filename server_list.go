@@ -1,6 +1,7 @@
 package mcrouterdiscovery
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -8,32 +9,51 @@ import (
 )
 
 type ServerListClient struct {
-	endpoint string
-	client   *http.Client
-	auth     Auth
+	endpoint    string
+	client      *http.Client
+	auth        Auth
+	retry       RetryPolicy
+	retryBudget *RetryBudget
+}
+
+type ServerListClientOpts struct {
+	Retry       RetryPolicy
+	RetryBudget *RetryBudget
 }
 
 func NewServerListClient(endpoint string, auth Auth) *ServerListClient {
+	return NewServerListClientWithOpts(endpoint, auth, ServerListClientOpts{})
+}
+
+func NewServerListClientWithOpts(endpoint string, auth Auth, opts ServerListClientOpts) *ServerListClient {
 	return &ServerListClient{
 		endpoint: endpoint,
 		client: &http.Client{
 			Timeout: 15 * time.Second,
 		},
-		auth: auth,
+		auth:        auth,
+		retry:       opts.Retry,
+		retryBudget: opts.RetryBudget,
 	}
 }
 
 func (c *ServerListClient) GetServers() (Routes, error) {
-	req, err := http.NewRequest(http.MethodGet, c.endpoint, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+	return c.GetServersContext(context.Background())
+}
 
-	if err := c.auth.AuthenticateRequest(req); err != nil {
-		return nil, fmt.Errorf("failed to authenticate request: %w", err)
-	}
+func (c *ServerListClient) GetServersContext(ctx context.Context) (Routes, error) {
+	resp, err := doWithRetry(ctx, c.client, c.retry, c.retryBudget, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		if err := c.auth.AuthenticateRequest(req); err != nil {
+			return nil, fmt.Errorf("failed to authenticate request: %w", err)
+		}
 
-	resp, err := c.client.Do(req)
+		return req, nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch server list: %w", err)
 	}
@@ -0,0 +1,55 @@
+package mcrouterdiscovery
+
+import (
+	"fmt"
+	"time"
+)
+
+// Status is a snapshot of the outcome of the most recent reconciles.
+type Status struct {
+	LastReconcile        time.Time `json:"lastReconcile"`
+	LastSuccess          time.Time `json:"lastSuccess"`
+	LastError            string    `json:"lastError,omitempty"`
+	DeletionGuardTripped bool      `json:"deletionGuardTripped"`
+	DesiredRoutes        int       `json:"desiredRoutes"`
+	ActualRoutes         int       `json:"actualRoutes"`
+	ServerListReachable  bool      `json:"serverListReachable"`
+	McRouterReachable    bool      `json:"mcRouterReachable"`
+	// Fallbacks lists the routes pointing at the fallback backend, sorted by
+	// server address.
+	Fallbacks []FallbackRoute `json:"fallbacks,omitempty"`
+	// Rejected lists the server list entries that failed validation.
+	Rejected []RejectedRoute `json:"rejected,omitempty"`
+	// Duplicates lists the server addresses listed with several backends.
+	Duplicates []DuplicateRoute `json:"duplicates,omitempty"`
+}
+
+// Ready reports whether the reconciler is keeping mc-router in sync. It is
+// not ready before the first successful reconcile, when that success is older
+// than stalenessWindow, when either side was unreachable on the last attempt
+// or when the deletion guard is refusing to apply. The reasons explain why it
+// is not ready.
+func (s Status) Ready(stalenessWindow time.Duration, now time.Time) (bool, []string) {
+	var reasons []string
+
+	if s.LastSuccess.IsZero() {
+		reasons = append(reasons, "no successful reconcile yet")
+	} else if stalenessWindow > 0 && now.Sub(s.LastSuccess) > stalenessWindow {
+		reasons = append(reasons, fmt.Sprintf("last successful reconcile was %s ago", now.Sub(s.LastSuccess).Round(time.Second)))
+	}
+
+	if !s.LastReconcile.IsZero() {
+		if !s.ServerListReachable {
+			reasons = append(reasons, "server list is unreachable")
+		}
+		if !s.McRouterReachable {
+			reasons = append(reasons, "mc-router is unreachable")
+		}
+	}
+
+	if s.DeletionGuardTripped {
+		reasons = append(reasons, "deletion guard tripped")
+	}
+
+	return len(reasons) == 0, reasons
+}
@@ -0,0 +1,140 @@
+package mcrouterdiscovery
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	ErrInvalidHostname = errors.New("invalid hostname")
+	ErrInvalidBackend  = errors.New("invalid backend")
+)
+
+// hostnameProfile maps hostnames the way clients resolve them. Underscores
+// are allowed since Docker and Compose service names may contain them.
+var hostnameProfile = idna.New(idna.MapForLookup(), idna.Transitional(false), idna.StrictDomainName(false))
+
+// RejectedRoute is a server list entry dropped by ValidateRoutes.
+type RejectedRoute struct {
+	Route  Route  `json:"route"`
+	Reason string `json:"reason"`
+	// Quarantined is set when the server address is valid but the backend
+	// is not. The route mc-router already has for the address is kept
+	// instead of being deleted.
+	Quarantined bool `json:"quarantined"`
+}
+
+// ValidateRoutes normalizes the server address and backend of every route,
+// see NormalizeHostname and NormalizeBackend. Routes that fail are returned
+// as rejected with the reason, the rest are returned in order.
+func ValidateRoutes(routes Routes) (Routes, []RejectedRoute) {
+	var valid Routes
+	var rejected []RejectedRoute
+
+	for _, route := range routes {
+		if !route.Default {
+			addr, err := NormalizeHostname(route.ServerAddress)
+			if err != nil {
+				rejected = append(rejected, RejectedRoute{Route: route, Reason: err.Error()})
+				continue
+			}
+			route.ServerAddress = addr
+		}
+
+		backend, err := NormalizeBackend(route.Backend)
+		if err != nil {
+			// A rejected default route is left alone in mc-router as well.
+			rejected = append(rejected, RejectedRoute{Route: route, Reason: err.Error(), Quarantined: true})
+			continue
+		}
+		route.Backend = backend
+
+		valid = append(valid, route)
+	}
+
+	return valid, rejected
+}
+
+// NormalizeHostname lowercases host, converts internationalized names to
+// punycode and strips a trailing dot. IP addresses are returned in their
+// canonical form.
+func NormalizeHostname(host string) (string, error) {
+	host = strings.TrimSuffix(strings.TrimSpace(host), ".")
+	if host == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalidHostname)
+	}
+
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return ip.String(), nil
+	}
+
+	ascii, err := hostnameProfile.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q: %w", ErrInvalidHostname, host, err)
+	}
+	if len(ascii) > 253 {
+		return "", fmt.Errorf("%w: %q is longer than 253 characters", ErrInvalidHostname, host)
+	}
+	for _, label := range strings.Split(ascii, ".") {
+		if !validLabel(label) {
+			return "", fmt.Errorf("%w: %q has an invalid label %q", ErrInvalidHostname, host, label)
+		}
+	}
+
+	return ascii, nil
+}
+
+// NormalizeBackend checks that backend is host:port with a port between 1
+// and 65535 and normalizes the host with NormalizeHostname.
+func NormalizeBackend(backend string) (string, error) {
+	host, port, err := net.SplitHostPort(strings.TrimSpace(backend))
+	if err != nil {
+		return "", fmt.Errorf("%w: %q is not host:port", ErrInvalidBackend, backend)
+	}
+
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("%w: %q has an invalid port", ErrInvalidBackend, backend)
+	}
+
+	host, err = NormalizeHostname(host)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidBackend, err)
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(n)), nil
+}
+
+func validLabel(label string) bool {
+	if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+
+	for _, c := range label {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *Reconciler) recordRejected(rejected []RejectedRoute) {
+	for _, rejection := range rejected {
+		slog.Warn("Rejected server list entry", "serverAddress", rejection.Route.ServerAddress, "backend", rejection.Route.Backend, "source", rejection.Route.Source, "reason", rejection.Reason, "quarantined", rejection.Quarantined)
+	}
+
+	r.Metrics.observeRejected(len(rejected))
+
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+
+	r.status.Rejected = rejected
+}
@@ -0,0 +1,149 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNormalizeHostname(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		expected    string
+		expectError bool
+	}{
+		{name: "already normalized", host: "lobby.example.com", expected: "lobby.example.com"},
+		{name: "mixed case", host: "Lobby.Example.COM", expected: "lobby.example.com"},
+		{name: "trailing dot", host: "lobby.example.com.", expected: "lobby.example.com"},
+		{name: "surrounding whitespace", host: " lobby.example.com ", expected: "lobby.example.com"},
+		{name: "internationalized", host: "Bücher.example.com", expected: "xn--bcher-kva.example.com"},
+		{name: "punycode", host: "xn--bcher-kva.example.com", expected: "xn--bcher-kva.example.com"},
+		{name: "single label with underscore", host: "mc_lobby", expected: "mc_lobby"},
+		{name: "ipv4", host: "10.0.0.1", expected: "10.0.0.1"},
+		{name: "ipv6", host: "2001:DB8::1", expected: "2001:db8::1"},
+		{name: "empty", host: "", expectError: true},
+		{name: "space", host: "lobby example.com", expectError: true},
+		{name: "empty label", host: "lobby..example.com", expectError: true},
+		{name: "leading hyphen", host: "-lobby.example.com", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, err := NormalizeHostname(tt.host)
+			if tt.expectError {
+				if !errors.Is(err, ErrInvalidHostname) {
+					t.Errorf("expected ErrInvalidHostname, got %q, %v", host, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if host != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, host)
+			}
+		})
+	}
+}
+
+func TestNormalizeBackend(t *testing.T) {
+	tests := []struct {
+		name        string
+		backend     string
+		expected    string
+		expectError bool
+	}{
+		{name: "hostname", backend: "Lobby:25565", expected: "lobby:25565"},
+		{name: "ipv4", backend: "10.0.0.1:25566", expected: "10.0.0.1:25566"},
+		{name: "ipv6", backend: "[2001:db8::1]:25565", expected: "[2001:db8::1]:25565"},
+		{name: "leading zeros in port", backend: "lobby:025565", expected: "lobby:25565"},
+		{name: "missing port", backend: "lobby", expectError: true},
+		{name: "empty", backend: "", expectError: true},
+		{name: "port out of range", backend: "lobby:70000", expectError: true},
+		{name: "named port", backend: "lobby:minecraft", expectError: true},
+		{name: "missing host", backend: ":25565", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, err := NormalizeBackend(tt.backend)
+			if tt.expectError {
+				if !errors.Is(err, ErrInvalidBackend) {
+					t.Errorf("expected ErrInvalidBackend, got %q, %v", backend, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if backend != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, backend)
+			}
+		})
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	valid, rejected := ValidateRoutes(Routes{
+		{ServerAddress: "Lobby.Example.com.", Backend: "Lobby:25565"},
+		{ServerAddress: "", Backend: "survival:25565"},
+		{ServerAddress: "creative.example.com", Backend: "creative"},
+		{Default: true, Backend: "limbo"},
+		{Default: true, Backend: "limbo:25565"},
+	})
+
+	expected := Routes{
+		{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+		{Default: true, Backend: "limbo:25565"},
+	}
+	if len(valid) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, valid)
+	}
+	for i, route := range expected {
+		if valid[i] != route {
+			t.Errorf("expected %v, got %v", route, valid[i])
+		}
+	}
+
+	if len(rejected) != 3 {
+		t.Fatalf("expected 3 rejected routes, got %v", rejected)
+	}
+	if rejected[0].Quarantined || rejected[0].Reason != "invalid hostname: empty" {
+		t.Errorf("expected the empty server address to be rejected, got %+v", rejected[0])
+	}
+	if !rejected[1].Quarantined || rejected[1].Route.ServerAddress != "creative.example.com" {
+		t.Errorf("expected the route without a port to be quarantined, got %+v", rejected[1])
+	}
+	if !rejected[2].Quarantined || !rejected[2].Route.Default {
+		t.Errorf("expected the default route without a port to be quarantined, got %+v", rejected[2])
+	}
+}
+
+func TestReconcilerRejectedRoutes(t *testing.T) {
+	mr := &mockMcRouter{routes: Routes{
+		{ServerAddress: "creative.example.com", Backend: "creative:25565"},
+		{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+	}}
+	reconciler := NewReconciler(&mockServerList{routes: Routes{
+		{ServerAddress: "Lobby.Example.com", Backend: "lobby:25565"},
+		{ServerAddress: "creative.example.com", Backend: "creative"},
+		{ServerAddress: "not a hostname", Backend: "survival:25565"},
+	}}, mr, 0)
+
+	if err := reconciler.Reconcile(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(mr.registered) != 1 || mr.registered[0].ServerAddress != "lobby.example.com" {
+		t.Errorf("expected only the normalized lobby route to be registered, got %v", mr.registered)
+	}
+	// The quarantined creative route is kept, survival is gone from the list.
+	if len(mr.deleted) != 1 || mr.deleted[0] != "survival.example.com" {
+		t.Errorf("expected only survival.example.com to be deleted, got %v", mr.deleted)
+	}
+
+	if rejected := reconciler.Status().Rejected; len(rejected) != 2 {
+		t.Errorf("expected 2 rejected routes in the status, got %v", rejected)
+	}
+}
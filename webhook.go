@@ -0,0 +1,301 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the request
+	// body, prefixed with "sha256=".
+	SignatureHeader = "X-Signature-256"
+
+	DefaultWebhookResyncInterval = 5 * time.Minute
+
+	maxWebhookBodySize = 1 << 20
+)
+
+var (
+	// ErrNoPushedRoutes is returned by a WebhookServerList without an
+	// upstream until its first push. Pushed routes only live in memory, so
+	// an empty list after a restart must not be mistaken for no servers.
+	ErrNoPushedRoutes = errors.New("no upstream server list and no routes pushed yet")
+)
+
+type WebhookAction string
+
+const (
+	WebhookUpsert WebhookAction = "upsert"
+	WebhookDelete WebhookAction = "delete"
+)
+
+// WebhookPayload is the body accepted by the webhook. Deletes only need the
+// server address of each route.
+type WebhookPayload struct {
+	Action WebhookAction `json:"action"`
+	Routes Routes        `json:"routes"`
+}
+
+// WebhookServerList overlays routes pushed to its webhook on top of an
+// upstream server list. The upstream is re-fetched every ResyncInterval as a
+// safety net, which replaces any changes pushed before the fetch started.
+type WebhookServerList struct {
+	secret         []byte
+	resyncInterval time.Duration
+	changes        chan struct{}
+	swapped        chan struct{}
+
+	mu        sync.Mutex
+	upstream  ContextServerList
+	base      Routes
+	fetchedAt time.Time
+	stale     bool
+	overlay   map[string]webhookChange
+	pushed    bool
+}
+
+type webhookChange struct {
+	route    Route
+	deleted  bool
+	received time.Time
+}
+
+// NewWebhookServerList creates a webhook server list verifying payloads with
+// secret. upstream may be nil, in which case only pushed routes are served
+// and GetServersContext fails with ErrNoPushedRoutes until the first push.
+func NewWebhookServerList(upstream ContextServerList, secret string, resyncInterval time.Duration) *WebhookServerList {
+	if resyncInterval <= 0 {
+		resyncInterval = DefaultWebhookResyncInterval
+	}
+
+	return &WebhookServerList{
+		upstream:       upstream,
+		secret:         []byte(secret),
+		resyncInterval: resyncInterval,
+		changes:        make(chan struct{}, 1),
+		swapped:        make(chan struct{}, 1),
+		stale:          true,
+		overlay:        make(map[string]webhookChange),
+	}
+}
+
+func (w *WebhookServerList) GetServers() (Routes, error) {
+	return w.GetServersContext(context.Background())
+}
+
+// GetServersContext returns the upstream routes with pushed changes applied,
+// re-fetching the upstream when it is due for a resync. If a resync fails
+// the previous upstream routes keep being served so pushed changes still
+// apply, it is only an error before the first successful fetch.
+func (w *WebhookServerList) GetServersContext(ctx context.Context) (Routes, error) {
+	if upstream, due := w.resyncDue(); upstream != nil && due {
+		started := time.Now()
+		routes, err := upstream.GetServersContext(ctx)
+
+		w.mu.Lock()
+		if err != nil {
+			fetched := !w.fetchedAt.IsZero()
+			w.mu.Unlock()
+			if !fetched {
+				return nil, fmt.Errorf("failed to fetch upstream server list: %w", err)
+			}
+			slog.Warn("Failed to resync upstream server list, serving pushed changes on the last fetch", "err", err)
+		} else {
+			w.base = routes
+			w.fetchedAt = started
+			w.stale = false
+			for addr, change := range w.overlay {
+				if change.received.Before(started) {
+					delete(w.overlay, addr)
+				}
+			}
+			w.mu.Unlock()
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.upstream == nil && !w.pushed {
+		return nil, ErrNoPushedRoutes
+	}
+
+	var routes Routes
+	for _, route := range w.base {
+		if _, ok := w.overlay[route.ServerAddress]; ok && route.ServerAddress != "" {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	for _, change := range w.overlay {
+		if !change.deleted {
+			routes = append(routes, change.route)
+		}
+	}
+	sortRoutes(routes)
+
+	return routes, nil
+}
+
+// Changes is signalled whenever a payload is accepted or the upstream
+// reports a change.
+func (w *WebhookServerList) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// SetUpstream replaces the upstream server list, which is fetched on the next
+// GetServers call. Pushed changes are kept until then.
+func (w *WebhookServerList) SetUpstream(upstream ContextServerList) {
+	w.mu.Lock()
+	w.upstream = upstream
+	w.stale = true
+	w.mu.Unlock()
+
+	select {
+	case w.swapped <- struct{}{}:
+	default:
+	}
+	w.notify()
+}
+
+// Watch forwards the upstream's change notifications, if it has any, until
+// ctx is done. The upstream is re-fetched on the next GetServers call after a
+// change.
+func (w *WebhookServerList) Watch(ctx context.Context) {
+	for {
+		// A nil channel never fires, for upstreams without notifications.
+		var changes <-chan struct{}
+		w.mu.Lock()
+		if notifier, ok := w.upstream.(ChangeNotifier); ok {
+			changes = notifier.Changes()
+		}
+		w.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.swapped:
+		case <-changes:
+			w.mu.Lock()
+			w.stale = true
+			w.mu.Unlock()
+			w.notify()
+		}
+	}
+}
+
+// ServeHTTP accepts signed WebhookPayloads.
+func (w *WebhookServerList) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize+1))
+	if err != nil {
+		http.Error(rw, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxWebhookBodySize {
+		http.Error(rw, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !w.validSignature(r.Header.Get(SignatureHeader), body) {
+		http.Error(rw, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(rw, fmt.Sprintf("invalid payload: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := w.apply(payload); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("Accepted webhook", "action", payload.Action, "routes", len(payload.Routes))
+	w.notify()
+	rw.WriteHeader(http.StatusAccepted)
+}
+
+func (w *WebhookServerList) apply(payload WebhookPayload) error {
+	if payload.Action != WebhookUpsert && payload.Action != WebhookDelete {
+		return fmt.Errorf("invalid action: %q (must be upsert or delete)", payload.Action)
+	}
+
+	for _, route := range payload.Routes {
+		if route.ServerAddress == "" {
+			return fmt.Errorf("route is missing serverAddress")
+		}
+		if payload.Action == WebhookUpsert && route.Backend == "" {
+			return fmt.Errorf("route %s is missing backend", route.ServerAddress)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	w.pushed = true
+	for _, route := range payload.Routes {
+		w.overlay[route.ServerAddress] = webhookChange{
+			route:    route,
+			deleted:  payload.Action == WebhookDelete,
+			received: now,
+		}
+	}
+
+	return nil
+}
+
+func (w *WebhookServerList) validSignature(header string, body []byte) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(got, SignWebhook(w.secret, body))
+}
+
+// resyncDue returns the upstream and whether it is due to be re-fetched.
+func (w *WebhookServerList) resyncDue() (ContextServerList, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.upstream, w.stale || time.Since(w.fetchedAt) >= w.resyncInterval
+}
+
+func (w *WebhookServerList) notify() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+	}
+}
+
+// SignWebhook returns the HMAC-SHA256 of body, senders hex encode it into
+// the SignatureHeader as "sha256=<hex>".
+func SignWebhook(secret, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
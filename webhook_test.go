@@ -0,0 +1,247 @@
+package mcrouterdiscovery
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingServerList struct {
+	routes Routes
+	err    error
+	calls  int
+}
+
+func (c *countingServerList) GetServersContext(ctx context.Context) (Routes, error) {
+	c.calls++
+	return c.routes, c.err
+}
+
+func postWebhook(t *testing.T, w *WebhookServerList, secret, body string) int {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	if secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(SignWebhook([]byte(secret), []byte(body))))
+	}
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestWebhookServerListServeHTTP(t *testing.T) {
+	tests := []struct {
+		name         string
+		secret       string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "valid upsert",
+			secret:       "secret",
+			body:         `{"action":"upsert","routes":[{"serverAddress":"lobby.example.com","backend":"lobby:25565"}]}`,
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "valid delete",
+			secret:       "secret",
+			body:         `{"action":"delete","routes":[{"serverAddress":"lobby.example.com"}]}`,
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "wrong secret",
+			secret:       "wrong",
+			body:         `{"action":"upsert","routes":[{"serverAddress":"lobby.example.com","backend":"lobby:25565"}]}`,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "missing signature",
+			body:         `{"action":"upsert","routes":[{"serverAddress":"lobby.example.com","backend":"lobby:25565"}]}`,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "invalid action",
+			secret:       "secret",
+			body:         `{"action":"rename","routes":[]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "upsert without backend",
+			secret:       "secret",
+			body:         `{"action":"upsert","routes":[{"serverAddress":"lobby.example.com"}]}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid json",
+			secret:       "secret",
+			body:         `{"action":`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWebhookServerList(nil, "secret", time.Minute)
+
+			if code := postWebhook(t, w, tt.secret, tt.body); code != tt.expectedCode {
+				t.Errorf("expected status %d, got %d", tt.expectedCode, code)
+			}
+
+			select {
+			case <-w.Changes():
+				if tt.expectedCode != http.StatusAccepted {
+					t.Error("expected no change notification for a rejected payload")
+				}
+			default:
+				if tt.expectedCode == http.StatusAccepted {
+					t.Error("expected a change notification")
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookServerListWithoutUpstream(t *testing.T) {
+	w := NewWebhookServerList(nil, "secret", time.Minute)
+
+	// Until something is pushed, an empty list would delete every route.
+	if _, err := w.GetServers(); !errors.Is(err, ErrNoPushedRoutes) {
+		t.Fatalf("expected ErrNoPushedRoutes before the first push, got %v", err)
+	}
+
+	postWebhook(t, w, "secret", `{"action":"upsert","routes":[{"serverAddress":"lobby.example.com","backend":"lobby:25565"}]}`)
+
+	routes, err := w.GetServers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(routes) != 1 || routes[0].ServerAddress != "lobby.example.com" {
+		t.Errorf("expected the pushed route, got %v", routes)
+	}
+}
+
+func TestWebhookServerListOverlay(t *testing.T) {
+	upstream := &countingServerList{
+		routes: Routes{
+			{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+			{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+		},
+	}
+	w := NewWebhookServerList(upstream, "secret", time.Hour)
+
+	postWebhook(t, w, "secret", `{"action":"upsert","routes":[{"serverAddress":"creative.example.com","backend":"creative:25565"},{"serverAddress":"lobby.example.com","backend":"lobby-2:25565"}]}`)
+	postWebhook(t, w, "secret", `{"action":"delete","routes":[{"serverAddress":"survival.example.com"}]}`)
+
+	routes, err := w.GetServers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := Routes{
+		{ServerAddress: "creative.example.com", Backend: "creative:25565"},
+		{ServerAddress: "lobby.example.com", Backend: "lobby:25565"},
+		{ServerAddress: "survival.example.com", Backend: "survival:25565"},
+	}
+	// The first fetch is a resync, which replaces changes pushed before it.
+	if len(routes) != 2 || routes[0] != expected[1] || routes[1] != expected[2] {
+		t.Errorf("expected the resync to replace earlier pushes, got %v", routes)
+	}
+
+	postWebhook(t, w, "secret", `{"action":"upsert","routes":[{"serverAddress":"creative.example.com","backend":"creative:25565"},{"serverAddress":"lobby.example.com","backend":"lobby-2:25565"}]}`)
+	postWebhook(t, w, "secret", `{"action":"delete","routes":[{"serverAddress":"survival.example.com"}]}`)
+
+	routes, err = w.GetServers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected = Routes{
+		{ServerAddress: "creative.example.com", Backend: "creative:25565"},
+		{ServerAddress: "lobby.example.com", Backend: "lobby-2:25565"},
+	}
+	if len(routes) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, routes)
+	}
+	for i, route := range expected {
+		if routes[i] != route {
+			t.Errorf("expected %v, got %v", route, routes[i])
+		}
+	}
+
+	if upstream.calls != 1 {
+		t.Errorf("expected the upstream to be fetched once before the resync interval, got %d", upstream.calls)
+	}
+}
+
+func TestWebhookServerListResync(t *testing.T) {
+	upstream := &countingServerList{
+		routes: Routes{{ServerAddress: "lobby.example.com", Backend: "lobby:25565"}},
+	}
+	w := NewWebhookServerList(upstream, "secret", time.Hour)
+
+	if _, err := w.GetServers(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	postWebhook(t, w, "secret", `{"action":"delete","routes":[{"serverAddress":"lobby.example.com"}]}`)
+
+	// Force the next call to resync, as if the interval had passed.
+	w.fetchedAt = time.Now().Add(-2 * time.Hour)
+	routes, err := w.GetServers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(routes) != 1 {
+		t.Errorf("expected the resync to restore the upstream route, got %v", routes)
+	}
+
+	// A failed resync keeps serving the last upstream routes with pushes.
+	upstream.err = errors.New("connection refused")
+	w.fetchedAt = time.Now().Add(-2 * time.Hour)
+	postWebhook(t, w, "secret", `{"action":"upsert","routes":[{"serverAddress":"creative.example.com","backend":"creative:25565"}]}`)
+	routes, err = w.GetServers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(routes) != 2 {
+		t.Errorf("expected the last upstream routes and the push, got %v", routes)
+	}
+}
+
+func TestWebhookServerListUpstreamError(t *testing.T) {
+	upstream := &countingServerList{err: errors.New("connection refused")}
+	w := NewWebhookServerList(upstream, "secret", time.Hour)
+
+	if _, err := w.GetServers(); err == nil {
+		t.Error("expected error before the first successful fetch")
+	}
+}
+
+func TestWebhookServerListSetUpstream(t *testing.T) {
+	w := NewWebhookServerList(&countingServerList{
+		routes: Routes{{ServerAddress: "lobby.example.com", Backend: "lobby:25565"}},
+	}, "secret", time.Hour)
+
+	if _, err := w.GetServers(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	postWebhook(t, w, "secret", `{"action":"upsert","routes":[{"serverAddress":"creative.example.com","backend":"creative:25565"}]}`)
+	w.SetUpstream(&countingServerList{
+		routes: Routes{{ServerAddress: "survival.example.com", Backend: "survival:25565"}},
+	})
+
+	routes, err := w.GetServers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Pushes received before the new upstream is fetched are replaced by it.
+	if len(routes) != 1 || routes[0].ServerAddress != "survival.example.com" {
+		t.Errorf("expected the routes of the new upstream, got %v", routes)
+	}
+}